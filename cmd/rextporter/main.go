@@ -1,19 +1,35 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/simelo/rextporter/src/exporter"
+	log "github.com/sirupsen/logrus"
 )
 
+// shutdownTimeout is how long to wait for the pending requests and the metrics state to be saved at shutdown.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
 	mainConfigFile := flag.String("config", "", "Metrics main config file path.")
 	defaultListenPort := 8080
 	listenPort := flag.Uint("port", uint(defaultListenPort), "Listen port.")
 	defaultHandlerEndpint := "/metrics"
 	handlerEndpint := flag.String("handler", defaultHandlerEndpint, "Handler endpoint.")
+	selfHandlerEndpint := flag.String("selfHandler", "", "Handler endpoint for the rextporter own metrics, they are served under the handler endpoint if empty.")
 	flag.Parse()
-	exporter.ExportMetrics(*mainConfigFile, *handlerEndpint, uint16(*listenPort))
-	waitForEver := make(chan bool)
-	<-waitForEver
+	srv := exporter.ExportMetrics(*mainConfigFile, *handlerEndpint, *selfHandlerEndpint, uint16(*listenPort))
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := exporter.Shutdown(ctx, srv); err != nil {
+		log.WithError(err).Errorln("unable to shutdown the server gracefully")
+	}
 }
@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/oliveagle/jsonpath"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
+	"github.com/simelo/rextporter/src/util"
+)
+
+// DataClient implements the getRemoteInfo method from `client.Client` interface by using some `.toml` config parameters
+// like for example: where is the host? it should be a GET, a POST or some other? ... and decode the json document
+// in the response, so all the metrics in the same endpoint can share it.
+// sa NewDataClient method.
+type DataClient struct {
+	BaseClient
+	// mutex protect the request and the token from concurrent updates.
+	mutex sync.Mutex
+	token string
+	// endpoint is the metric url, used to identify the requests in the rextporter own metrics.
+	endpoint string
+}
+
+// NewDataClient will put all the required info to be able to do http requests to get the remote data
+// for the metric endpoint.
+func NewDataClient(metric config.Metric, service config.Service) (client *DataClient, err error) {
+	const generalScopeErr = "error creating a client to get data from remote endpoint"
+	client = new(DataClient)
+	client.BaseClient.service = service
+	client.endpoint = metric.URL
+	client.BaseClient.req, err = http.NewRequest(metric.HTTPMethod, client.service.URIToGetMetric(metric), nil)
+	if err != nil {
+		errCause := fmt.Sprintln("can not create the request: ", err.Error())
+		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return client, nil
+}
+
+func (client *DataClient) resetToken(ctx context.Context) (err error) {
+	const generalScopeErr = "error making resetting the token"
+	client.token = ""
+	selfmetrics.TokenRefresh(client.service.Name)
+	var clientToken *TokenClient
+	if clientToken, err = newTokenClient(client.service); err != nil {
+		errCause := fmt.Sprintln("can not find a host: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	var data []byte
+	if data, err = clientToken.getRemoteInfo(ctx); err != nil {
+		errCause := fmt.Sprintln("can make the request to get a token: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	var jsonData interface{}
+	if err = json.Unmarshal(data, &jsonData); err != nil {
+		errCause := fmt.Sprintln("can not decode the body: ", string(data), " ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	var val interface{}
+	jPath := "$" + strings.Replace(client.service.TokenKeyFromEndpoint, "/", ".", -1)
+	if val, err = jsonpath.JsonPathLookup(jsonData, jPath); err != nil {
+		errCause := fmt.Sprintln("can not locate the path: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	tk, ok := val.(string)
+	if !ok {
+		errCause := fmt.Sprintln("unable the get the token as a string value")
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	client.token = tk
+	if len(client.token) == 0 {
+		errCause := fmt.Sprintln("unable the get a not null(empty) token")
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return nil
+}
+
+// ResponseInfo has some details about the response got from the remote endpoint.
+type ResponseInfo struct {
+	// StatusCode is the http status code, 0 if there was not a response.
+	StatusCode int
+	// Size is the response body length in bytes.
+	Size int
+}
+
+func (client *DataClient) getRemoteInfo(ctx context.Context) (data []byte, info ResponseInfo, err error) {
+	const generalScopeErr = "error making a server request to get metric from remote endpoint"
+	doRequest := func() (*http.Response, error) {
+		client.req.Header.Set(client.service.TokenHeaderKey, client.token)
+		httpClient := &http.Client{}
+		var resp *http.Response
+		if resp, err = httpClient.Do(client.req.WithContext(ctx)); err != nil {
+			selfmetrics.UpstreamRequest(client.service.Name, client.endpoint, 0)
+			errCause := fmt.Sprintln("can not do the request: ", err.Error())
+			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		selfmetrics.UpstreamRequest(client.service.Name, client.endpoint, resp.StatusCode)
+		return resp, nil
+	}
+	var resp *http.Response
+	if resp, err = doRequest(); err != nil {
+		// log.Println("can not do the request:", err.Error(), "trying with a new token...")
+		if err = client.resetToken(ctx); err != nil {
+			errCause := fmt.Sprintln("can not reset the token: ", err.Error())
+			return nil, info, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		if resp, err = doRequest(); err != nil {
+			errCause := fmt.Sprintln("can not do the request after a token reset neither: ", err.Error())
+			return nil, info, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+	}
+	defer resp.Body.Close()
+	info.StatusCode = resp.StatusCode
+	if data, err = ioutil.ReadAll(resp.Body); err != nil {
+		errCause := fmt.Sprintln("can not read the body: ", err.Error())
+		return nil, info, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	info.Size = len(data)
+	if resp.StatusCode >= http.StatusBadRequest {
+		errCause := fmt.Sprintln("the response status is: ", resp.Status)
+		return nil, info, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return data, info, nil
+}
+
+// Service returns the config for the service this client get the data from.
+func (client *DataClient) Service() config.Service {
+	return client.service
+}
+
+// GetData returns the decoded json document from the remote endpoint, the remote requests are canceled if
+// ctx is done before they finish, it is safe to be called concurrently.
+func (client *DataClient) GetData(ctx context.Context) (jsonData interface{}, err error) {
+	jsonData, _, err = client.GetDataWithResponseInfo(ctx)
+	return jsonData, err
+}
+
+// GetDataWithResponseInfo works like GetData but returning some details about the response too, info
+// is filled as much as possible even if there is an error. A response with an error status(400 or
+// greater) is an error too, even if the body is a json document.
+func (client *DataClient) GetDataWithResponseInfo(ctx context.Context) (jsonData interface{}, info ResponseInfo, err error) {
+	const generalScopeErr = "error getting remote data"
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	var data []byte
+	if data, info, err = client.getRemoteInfo(ctx); err != nil {
+		return nil, info, util.ErrorFromThisScope(err.Error(), generalScopeErr)
+	}
+	if err = json.Unmarshal(data, &jsonData); err != nil {
+		selfmetrics.DecodeFailure(client.service.Name, client.endpoint)
+		errCause := fmt.Sprintln("can not decode the body: ", string(data), " ", err.Error())
+		return nil, info, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return jsonData, info, nil
+}
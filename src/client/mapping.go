@@ -0,0 +1,93 @@
+package client
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/simelo/rextporter/src/config"
+)
+
+// valueMapper get numbers from string values according to the metric mapping options,
+// sa config.MappingOptions.
+type valueMapper struct {
+	values map[string]float64
+	states []string
+	regex  *regexp.Regexp
+}
+
+// newValueMapper returns a mapper for options, or nil if there is not a mapping defined.
+func newValueMapper(options config.MappingOptions) (mapper *valueMapper) {
+	if len(options.Values) == 0 && len(options.States) == 0 && len(options.Regex) == 0 {
+		return nil
+	}
+	mapper = &valueMapper{states: options.States}
+	if len(options.Values) != 0 {
+		mapper.values = make(map[string]float64, len(options.Values))
+		for _, mapping := range options.Values {
+			mapper.values[mapping.Value] = mapping.Number
+		}
+	}
+	if len(options.Regex) != 0 {
+		// NOTE(denisacostaq@gmail.com): the regex was checked in the config validation
+		mapper.regex = regexp.MustCompile(options.Regex)
+	}
+	return mapper
+}
+
+// unknownValueError is returned if a value is not in the mapping.
+type unknownValueError struct {
+	val interface{}
+}
+
+func (err unknownValueError) Error() string {
+	return fmt.Sprintf("the value %v is not in the mapping", err.val)
+}
+
+// mapValue returns the number for val from the values table or the regex, arrays are mapped item by item.
+func (mapper *valueMapper) mapValue(val interface{}) (number interface{}, err error) {
+	if items, ok := val.([]interface{}); ok {
+		numbers := make([]interface{}, len(items))
+		for idxItem, item := range items {
+			if numbers[idxItem], err = mapper.mapValue(item); err != nil {
+				return nil, err
+			}
+		}
+		return numbers, nil
+	}
+	str := fmt.Sprint(val)
+	if mapper.values != nil {
+		if number, ok := mapper.values[str]; ok {
+			return number, nil
+		}
+		return nil, unknownValueError{val: val}
+	}
+	if mapper.regex != nil {
+		matches := mapper.regex.FindStringSubmatch(str)
+		if len(matches) != 2 {
+			return nil, unknownValueError{val: val}
+		}
+		var f float64
+		if f, err = strconv.ParseFloat(matches[1], 64); err != nil {
+			return nil, unknownValueError{val: val}
+		}
+		return f, nil
+	}
+	return val, nil
+}
+
+// stateValues returns a value for each state with the state as the last label value, 1 for the state
+// in val.Val and 0 for the others. known is false if val.Val is not one of the states.
+func (mapper *valueMapper) stateValues(val LabeledValue) (vals []LabeledValue, known bool) {
+	str := fmt.Sprint(val.Val)
+	vals = make([]LabeledValue, len(mapper.states))
+	for idxState, state := range mapper.states {
+		vals[idxState].LabelValues = append(append([]string{}, val.LabelValues...), state)
+		vals[idxState].Val = float64(0)
+		if state == str {
+			vals[idxState].Val = float64(1)
+			known = true
+		}
+	}
+	return vals, known
+}
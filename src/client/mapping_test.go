@@ -0,0 +1,88 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/suite"
+)
+
+type valueMapperSuit struct {
+	suite.Suite
+}
+
+func TestValueMapperSuit(t *testing.T) {
+	suite.Run(t, new(valueMapperSuit))
+}
+
+func (suite *valueMapperSuit) TestUndefinedMapping() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	options := config.MappingOptions{}
+
+	// NOTE(denisacostaq@gmail.com): When
+	mapper := newValueMapper(options)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(mapper)
+}
+
+func (suite *valueMapperSuit) TestValues() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	mapper := newValueMapper(config.MappingOptions{
+		Values: []config.ValueMapping{
+			config.ValueMapping{Value: "healthy", Number: 1},
+			config.ValueMapping{Value: "degraded", Number: 0.5},
+		},
+	})
+
+	// NOTE(denisacostaq@gmail.com): When
+	val, err := mapper.mapValue("degraded")
+	vals, valsErr := mapper.mapValue([]interface{}{"healthy", "degraded"})
+	_, unknownErr := mapper.mapValue("down")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal(0.5, val)
+	suite.Nil(valsErr)
+	suite.Equal([]interface{}{float64(1), 0.5}, vals)
+	suite.NotNil(unknownErr)
+}
+
+func (suite *valueMapperSuit) TestRegex() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	mapper := newValueMapper(config.MappingOptions{Regex: `^\d+\.(\d+)\.\d+$`})
+
+	// NOTE(denisacostaq@gmail.com): When
+	val, err := mapper.mapValue("0.24.1")
+	_, unknownErr := mapper.mapValue("develop")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal(float64(24), val)
+	suite.NotNil(unknownErr)
+}
+
+func (suite *valueMapperSuit) TestStates() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	mapper := newValueMapper(config.MappingOptions{States: []string{"syncing", "synced"}})
+
+	// NOTE(denisacostaq@gmail.com): When
+	vals, known := mapper.stateValues(LabeledValue{LabelValues: []string{"node1"}, Val: "synced"})
+	unknownVals, unknown := mapper.stateValues(LabeledValue{Val: "stopped"})
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(known)
+	suite.Equal(
+		[]LabeledValue{
+			LabeledValue{LabelValues: []string{"node1", "syncing"}, Val: float64(0)},
+			LabeledValue{LabelValues: []string{"node1", "synced"}, Val: float64(1)},
+		},
+		vals)
+	suite.False(unknown)
+	suite.Equal(
+		[]LabeledValue{
+			LabeledValue{LabelValues: []string{"syncing"}, Val: float64(0)},
+			LabeledValue{LabelValues: []string{"synced"}, Val: float64(0)},
+		},
+		unknownVals)
+}
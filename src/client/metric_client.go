@@ -1,14 +1,18 @@
 package client
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/oliveagle/jsonpath"
 	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
 	"github.com/simelo/rextporter/src/util"
 )
 
@@ -19,114 +23,299 @@ type BaseClient struct {
 	service config.Service
 }
 
-// MetricClient implements the getRemoteInfo method from `client.Client` interface by using some `.toml` config parameters
-// like for example: where is the host? it should be a GET, a POST or some other? ...
+// MetricClient get a metric value from the json document of his DataClient by using some `.toml` config
+// parameters like for example: where is the value inside the document? it has labels? ...
 // sa NewMetricClient method.
 type MetricClient struct {
-	BaseClient
-	token       string
+	dataClient  *DataClient
 	metricJPath string
+	itemJPath   string
+	labels      []config.Label
+	valueType   string
+	// isInfo and maxValueLength are used for info metrics, sa config.KeyTypeInfo.
+	isInfo         bool
+	maxValueLength int
+	// metricName and mapper are used for metrics who map string values to numbers, sa config.MappingOptions.
+	metricName string
+	mapper     *valueMapper
+	// timestampJPath is where to find the samples time, sa config.Metric.TimestampPath.
+	timestampJPath string
+	// timestampUnit is the unit for the unix times, sa config.Metric.TimestampUnit.
+	timestampUnit string
+	// timestampMutex protect timestampChecked and invalidTimestamp, the timestamp path is checked against
+	// the first response where it is found, if it is not a time the metric is invalid, sa checkTimestamp.
+	timestampMutex   sync.Mutex
+	timestampChecked bool
+	invalidTimestamp error
+}
+
+// LabeledValue is the value found for an item in a labeled metric, the label values are in the
+// same order than the labels in the metric config.
+type LabeledValue struct {
+	LabelValues []string
+	Val         interface{}
 }
 
 // NewMetricClient will put all the required info to be able to do http requests to get the remote data.
 func NewMetricClient(metric config.Metric, service config.Service) (client *MetricClient, err error) {
 	const generalScopeErr = "error creating a client to get a metric from remote endpoint"
-	client = new(MetricClient)
-	client.BaseClient.service = service
-	client.metricJPath = metric.Path
-	client.BaseClient.req, err = http.NewRequest(metric.HTTPMethod, client.service.URIToGetMetric(metric), nil)
-	if err != nil {
-		errCause := fmt.Sprintln("can not create the request: ", err.Error())
+	var dataClient *DataClient
+	if dataClient, err = NewDataClient(metric, service); err != nil {
+		errCause := fmt.Sprintln("can not create the data client: ", err.Error())
 		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
-	return client, nil
+	return NewMetricClientFromDataClient(metric, dataClient), nil
 }
 
-func (client *MetricClient) resetToken() (err error) {
-	const generalScopeErr = "error making resetting the token"
-	client.token = ""
-	var clientToken *TokenClient
-	if clientToken, err = newTokenClient(client.service); err != nil {
-		errCause := fmt.Sprintln("can not find a host: ", err.Error())
-		return util.ErrorFromThisScope(errCause, generalScopeErr)
-	}
-	var data []byte
-	if data, err = clientToken.getRemoteInfo(); err != nil {
-		errCause := fmt.Sprintln("can make the request to get a token: ", err.Error())
-		return util.ErrorFromThisScope(errCause, generalScopeErr)
-	}
-	var jsonData interface{}
-	if err = json.Unmarshal(data, &jsonData); err != nil {
-		errCause := fmt.Sprintln("can not decode the body: ", string(data), " ", err.Error())
-		return util.ErrorFromThisScope(errCause, generalScopeErr)
-	}
-	var val interface{}
-	jPath := "$" + strings.Replace(client.service.TokenKeyFromEndpoint, "/", ".", -1)
-	if val, err = jsonpath.JsonPathLookup(jsonData, jPath); err != nil {
-		errCause := fmt.Sprintln("can not locate the path: ", err.Error())
-		return util.ErrorFromThisScope(errCause, generalScopeErr)
+// NewMetricClientFromDataClient create a metric client who get the remote data through dataClient, so you
+// can share a single request between all the metrics in the same endpoint.
+func NewMetricClientFromDataClient(metric config.Metric, dataClient *DataClient) (client *MetricClient) {
+	client = new(MetricClient)
+	client.dataClient = dataClient
+	client.metricJPath = metric.Path
+	client.itemJPath = metric.ItemPath
+	client.labels = metric.Labels
+	client.valueType = metric.ValueTypeOrDefault()
+	client.isInfo = metric.IsInfo()
+	client.maxValueLength = metric.InfoOptions.MaxValueLength
+	client.metricName = metric.Name
+	client.mapper = newValueMapper(metric.MappingOptions)
+	client.timestampJPath = metric.TimestampPath
+	client.timestampUnit = metric.TimestampUnitOrDefault()
+	return client
+}
+
+// DataClient returns the client used to get the remote data for this metric.
+func (client *MetricClient) DataClient() *DataClient {
+	return client.dataClient
+}
+
+// jsonPath translate a config path like "/blockchain/head/seq" in a json path like "$.blockchain.head.seq"
+func jsonPath(path string) string {
+	return "$" + strings.Replace(path, "/", ".", -1)
+}
+
+// labeledValue look for the label values and the value inside item
+func (client *MetricClient) labeledValue(key string, item interface{}) (val LabeledValue, err error) {
+	val.LabelValues = make([]string, len(client.labels))
+	for idxLabel, label := range client.labels {
+		if label.Path == config.KeyLabelPathItemKey {
+			val.LabelValues[idxLabel] = key
+			continue
+		}
+		var labelVal interface{}
+		if labelVal, err = jsonpath.JsonPathLookup(item, jsonPath(label.Path)); err != nil {
+			return val, fmt.Errorf("can not locate the path for label %s: %s", label.Name, err.Error())
+		}
+		if strVal, ok := labelVal.(string); ok {
+			val.LabelValues[idxLabel] = strVal
+		} else {
+			val.LabelValues[idxLabel] = fmt.Sprint(labelVal)
+		}
 	}
-	tk, ok := val.(string)
-	if !ok {
-		errCause := fmt.Sprintln("unable the get the token as a string value")
-		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	val.Val = item
+	if len(client.itemJPath) != 0 {
+		if val.Val, err = jsonpath.JsonPathLookup(item, jsonPath(client.itemJPath)); err != nil {
+			return val, fmt.Errorf("can not locate the item path: %s", err.Error())
+		}
 	}
-	client.token = tk
-	if len(client.token) == 0 {
-		errCause := fmt.Sprintln("unable the get a not null(empty) token")
-		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	if val.Val, err = client.coerceValue(val.Val); err != nil {
+		return val, err
 	}
-	return nil
+	return val, nil
 }
 
-func (client *MetricClient) getRemoteInfo() (data []byte, err error) {
-	const generalScopeErr = "error making a server request to get metric from remote endpoint"
-	client.req.Header.Set(client.service.TokenHeaderKey, client.token)
-	doRequest := func() (*http.Response, error) {
-		httpClient := &http.Client{}
-		var resp *http.Response
-		if resp, err = httpClient.Do(client.req); err != nil {
-			errCause := fmt.Sprintln("can not do the request: ", err.Error())
-			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+// stateValues expand each value in vals to a value for each state, sa valueMapper.stateValues.
+// The values who are not one of the states are counted as unknown, but they are not an error.
+func (client *MetricClient) stateValues(vals []LabeledValue) (stateVals []LabeledValue) {
+	stateVals = make([]LabeledValue, 0, len(vals)*len(client.mapper.states))
+	for _, val := range vals {
+		expanded, known := client.mapper.stateValues(val)
+		if !known {
+			selfmetrics.UnknownValue(client.dataClient.service.Name, client.metricName)
 		}
-		return resp, nil
+		stateVals = append(stateVals, expanded...)
 	}
-	var resp *http.Response
-	if resp, err = doRequest(); err != nil {
-		// log.Println("can not do the request:", err.Error(), "trying with a new token...")
-		if err = client.resetToken(); err != nil {
-			errCause := fmt.Sprintln("can not reset the token: ", err.Error())
-			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	return stateVals
+}
+
+// labeledValues iterate over the items in collection(an array or an object) to get a value for each one.
+func (client *MetricClient) labeledValues(collection interface{}) (vals []LabeledValue, err error) {
+	switch items := collection.(type) {
+	case []interface{}:
+		vals = make([]LabeledValue, len(items))
+		for idxItem, item := range items {
+			if vals[idxItem], err = client.labeledValue(strconv.Itoa(idxItem), item); err != nil {
+				return nil, err
+			}
 		}
-		if resp, err = doRequest(); err != nil {
-			errCause := fmt.Sprintln("can not do the request after a token reset neither: ", err.Error())
-			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(items))
+		for key := range items {
+			keys = append(keys, key)
 		}
+		sort.Strings(keys)
+		vals = make([]LabeledValue, len(keys))
+		for idxKey, key := range keys {
+			if vals[idxKey], err = client.labeledValue(key, items[key]); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("a labeled metric path should refer to an array or an object, not to %v", collection)
 	}
-	if data, err = ioutil.ReadAll(resp.Body); err != nil {
-		errCause := fmt.Sprintln("can not read the body: ", err.Error())
-		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	return vals, nil
+}
+
+// infoValue returns a single labeled value with a constant 1 value and the label values taken from object,
+// the label values longer than the max value length(if any) are truncated.
+func (client *MetricClient) infoValue(object interface{}) (vals []LabeledValue, err error) {
+	if _, ok := object.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("an info metric path should refer to an object, not to %v", object)
+	}
+	var val LabeledValue
+	if val, err = client.labeledValue("", object); err != nil {
+		return nil, err
 	}
-	return data, nil
+	val.Val = float64(1)
+	if client.maxValueLength > 0 {
+		for idxLabel, labelVal := range val.LabelValues {
+			if runes := []rune(labelVal); len(runes) > client.maxValueLength {
+				val.LabelValues[idxLabel] = string(runes[:client.maxValueLength])
+			}
+		}
+	}
+	return []LabeledValue{val}, nil
 }
 
 // GetMetric returns the metric previously bound through config parameters like:
 // url(endpoint), json path, type and so on.
+// If the metric has labels the returned value is a []LabeledValue, one for each item in the metric path.
 func (client *MetricClient) GetMetric() (val interface{}, err error) {
+	return client.GetMetricWithContext(context.Background())
+}
+
+// GetMetricWithContext works like GetMetric but the remote requests are canceled if ctx is done before
+// they finish, it is safe to be called concurrently.
+func (client *MetricClient) GetMetricWithContext(ctx context.Context) (val interface{}, err error) {
 	const generalScopeErr = "error getting metric data"
-	var data []byte
-	if data, err = client.getRemoteInfo(); err != nil {
+	var jsonData interface{}
+	if jsonData, err = client.dataClient.GetData(ctx); err != nil {
 		return nil, util.ErrorFromThisScope(err.Error(), generalScopeErr)
 	}
-	var jsonData interface{}
-	if err = json.Unmarshal(data, &jsonData); err != nil {
-		errCause := fmt.Sprintln("can not decode the body: ", string(data), " ", err.Error())
+	return client.ValueFromData(jsonData)
+}
+
+// ValueFromData returns the metric value from a json document previously got through the metric DataClient,
+// so many metrics can be evaluated against the same document.
+func (client *MetricClient) ValueFromData(jsonData interface{}) (val interface{}, err error) {
+	const generalScopeErr = "error getting metric data"
+	if val, err = jsonpath.JsonPathLookup(jsonData, jsonPath(client.metricJPath)); err != nil {
+		selfmetrics.PathLookupFailure(client.dataClient.service.Name, client.dataClient.endpoint)
+		errCause := fmt.Sprintln("can not locate the path: ", err.Error())
 		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
-	jPath := "$" + strings.Replace(client.metricJPath, "/", ".", -1)
-	if val, err = jsonpath.JsonPathLookup(jsonData, jPath); err != nil {
-		errCause := fmt.Sprintln("can not locate the path: ", err.Error())
+	if client.isInfo {
+		if val, err = client.infoValue(val); err != nil {
+			selfmetrics.PathLookupFailure(client.dataClient.service.Name, client.dataClient.endpoint)
+			errCause := fmt.Sprintln("can not get the info values: ", err.Error())
+			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		return val, nil
+	}
+	if len(client.labels) != 0 {
+		if val, err = client.labeledValues(val); err != nil {
+			selfmetrics.PathLookupFailure(client.dataClient.service.Name, client.dataClient.endpoint)
+			errCause := fmt.Sprintln("can not get the labeled values: ", err.Error())
+			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		if client.hasStates() {
+			return client.stateValues(val.([]LabeledValue)), nil
+		}
+		return val, nil
+	}
+	if client.hasStates() {
+		return client.stateValues([]LabeledValue{LabeledValue{Val: val}}), nil
+	}
+	if val, err = client.coerceValue(val); err != nil {
+		errCause := fmt.Sprintln("can not convert the value: ", err.Error())
 		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
 	return val, nil
 }
+
+// HasTimestamp returns true if the metric samples have an upstream time, sa TimestampFromData.
+func (client *MetricClient) HasTimestamp() bool {
+	return len(client.timestampJPath) != 0
+}
+
+// TimestampFromData returns the time for the metric samples from a json document previously got through
+// the metric DataClient.
+func (client *MetricClient) TimestampFromData(jsonData interface{}) (timestamp time.Time, err error) {
+	const generalScopeErr = "error getting metric timestamp"
+	if err = client.timestampErr(); err != nil {
+		return timestamp, util.ErrorFromThisScope(err.Error(), generalScopeErr)
+	}
+	var val interface{}
+	if val, err = jsonpath.JsonPathLookup(jsonData, jsonPath(client.timestampJPath)); err != nil {
+		selfmetrics.PathLookupFailure(client.dataClient.service.Name, client.dataClient.endpoint)
+		errCause := fmt.Sprintln("can not locate the timestamp path: ", err.Error())
+		return timestamp, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	timestamp, err = parseTimestamp(val, client.timestampUnit)
+	if err = client.checkTimestamp(err); err != nil {
+		errCause := fmt.Sprintln("can not parse the timestamp: ", err.Error())
+		return timestamp, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return timestamp, nil
+}
+
+// timestampErr returns an error if the timestamp path was found invalid before, sa checkTimestamp.
+func (client *MetricClient) timestampErr() error {
+	client.timestampMutex.Lock()
+	defer client.timestampMutex.Unlock()
+	return client.invalidTimestamp
+}
+
+// checkTimestamp mark the timestamp path as invalid if parseErr is the error for the first value found
+// in it, so a path to a field who is not a time(like a version) makes the metric fail in all the scrapes
+// instead of only when the field can not be parsed. The later parse errors are returned as is.
+func (client *MetricClient) checkTimestamp(parseErr error) error {
+	client.timestampMutex.Lock()
+	defer client.timestampMutex.Unlock()
+	if client.timestampChecked {
+		return parseErr
+	}
+	client.timestampChecked = true
+	if parseErr != nil {
+		client.invalidTimestamp = fmt.Errorf(
+			"invalid metric config, the timestampPath %s does not refer to a time: %s",
+			client.timestampJPath, parseErr.Error())
+		return client.invalidTimestamp
+	}
+	return nil
+}
+
+// hasStates returns true if the metric have a series for each state, sa config.MappingOptions.
+func (client *MetricClient) hasStates() bool {
+	return client.mapper != nil && len(client.mapper.states) != 0
+}
+
+// coerceValue convert val to a number according to the metric value type or mapping, json numbers
+// are returned as is. The values who are not in the mapping are counted as unknown.
+func (client *MetricClient) coerceValue(val interface{}) (number interface{}, err error) {
+	if client.hasStates() {
+		// NOTE(denisacostaq@gmail.com): the state values are expanded after the item lookup
+		return val, nil
+	}
+	if client.mapper != nil {
+		if number, err = client.mapper.mapValue(val); err != nil {
+			selfmetrics.UnknownValue(client.dataClient.service.Name, client.metricName)
+		}
+		return number, err
+	}
+	if client.valueType == config.KeyValueTypeNumber {
+		return val, nil
+	}
+	return coerceValue(client.valueType, client.timestampUnit, val)
+}
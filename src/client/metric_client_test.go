@@ -1,10 +1,12 @@
 package client
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/simelo/rextporter/src/config"
 	log "github.com/sirupsen/logrus"
@@ -1079,3 +1081,345 @@ func (suite *SkycoinStatsSuit) TestMetricJsonRpcEnabled() {
 	// NOTE(denisacostaq@gmail.com): Assert
 	suite.Equal(false, val)
 }
+
+func (suite *SkycoinStatsSuit) TestMetricCsrfEnabledAsBool() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var tomlConfig = `
+	# Service configuration.
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = 8080
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "localhost"
+
+		# All metrics to be measured.
+		[[services.metrics]]
+			name = "csrf_enabled"
+			url = "/api/v1/health"
+			httpMethod = "GET"
+			path = "csrf_enabled"
+			valueType = "bool"
+
+			[services.metrics.options]
+				type = "Gauge"
+				description = "CSRF enabled, 1 for true, 0 for false"
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	conf := config.Config()
+	require.Len(conf.Services, 1)
+	require.Len(conf.Services[0].Metrics, 1)
+	mc, err := NewMetricClient(conf.Services[0].Metrics[0], conf.Services[0])
+	require.Nil(err, "Can not crate the metric")
+
+	// NOTE(denisacostaq@gmail.com): When
+	var val interface{}
+	val, err = mc.GetMetric()
+	require.Nil(err, "Can not get the metric")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(float64(1), val)
+}
+
+func (suite *SkycoinStatsSuit) TestMetricUptimeAsDuration() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var tomlConfig = `
+	# Service configuration.
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = 8080
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "localhost"
+
+		# All metrics to be measured.
+		[[services.metrics]]
+			name = "uptime"
+			url = "/api/v1/health"
+			httpMethod = "GET"
+			path = "uptime"
+			valueType = "duration"
+
+			[services.metrics.options]
+				type = "Gauge"
+				description = "Uptime in seconds"
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	conf := config.Config()
+	require.Len(conf.Services, 1)
+	require.Len(conf.Services[0].Metrics, 1)
+	mc, err := NewMetricClient(conf.Services[0].Metrics[0], conf.Services[0])
+	require.Nil(err, "Can not crate the metric")
+
+	// NOTE(denisacostaq@gmail.com): When
+	var val interface{}
+	val, err = mc.GetMetric()
+	require.Nil(err, "Can not get the metric")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.InDelta(390.629057248, val, 1e-9)
+}
+
+func (suite *SkycoinStatsSuit) TestMetricTimeSinceLastBlockAsDuration() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var tomlConfig = `
+	# Service configuration.
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = 8080
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "localhost"
+
+		# All metrics to be measured.
+		[[services.metrics]]
+			name = "time_since_last_block"
+			url = "/api/v1/health"
+			httpMethod = "GET"
+			path = "/blockchain/time_since_last_block"
+			valueType = "duration"
+
+			[services.metrics.options]
+				type = "Gauge"
+				description = "Time since last block in seconds"
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	conf := config.Config()
+	require.Len(conf.Services, 1)
+	require.Len(conf.Services[0].Metrics, 1)
+	mc, err := NewMetricClient(conf.Services[0].Metrics[0], conf.Services[0])
+	require.Nil(err, "Can not crate the metric")
+
+	// NOTE(denisacostaq@gmail.com): When
+	var val interface{}
+	val, err = mc.GetMetric()
+	require.Nil(err, "Can not get the metric")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(float64(286), val)
+}
+
+func (suite *SkycoinStatsSuit) TestMetricVersionMinorFromRegex() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var tomlConfig = `
+	# Service configuration.
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = 8080
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "localhost"
+
+		# All metrics to be measured.
+		[[services.metrics]]
+			name = "version_minor"
+			url = "/api/v1/health"
+			httpMethod = "GET"
+			path = "/version/version"
+
+			[services.metrics.options]
+				type = "Gauge"
+				description = "Minor version"
+
+			[services.metrics.mappingOptions]
+				regex = '^\d+\.(\d+)\.\d+$'
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	conf := config.Config()
+	require.Len(conf.Services, 1)
+	require.Len(conf.Services[0].Metrics, 1)
+	mc, err := NewMetricClient(conf.Services[0].Metrics[0], conf.Services[0])
+	require.Nil(err, "Can not crate the metric")
+
+	// NOTE(denisacostaq@gmail.com): When
+	var val interface{}
+	val, err = mc.GetMetric()
+	require.Nil(err, "Can not get the metric")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(float64(24), val)
+}
+
+func (suite *SkycoinStatsSuit) TestMetricBranchAsStates() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var tomlConfig = `
+	# Service configuration.
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = 8080
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "localhost"
+
+		# All metrics to be measured.
+		[[services.metrics]]
+			name = "branch"
+			url = "/api/v1/health"
+			httpMethod = "GET"
+			path = "/version/branch"
+
+			[services.metrics.options]
+				type = "Gauge"
+				description = "Branch from which the node was built"
+
+			[services.metrics.mappingOptions]
+				states = ["master", "develop"]
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	conf := config.Config()
+	require.Len(conf.Services, 1)
+	require.Len(conf.Services[0].Metrics, 1)
+	mc, err := NewMetricClient(conf.Services[0].Metrics[0], conf.Services[0])
+	require.Nil(err, "Can not crate the metric")
+
+	// NOTE(denisacostaq@gmail.com): When
+	var val interface{}
+	val, err = mc.GetMetric()
+	require.Nil(err, "Can not get the metric")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(
+		[]LabeledValue{
+			LabeledValue{LabelValues: []string{"master"}, Val: float64(0)},
+			LabeledValue{LabelValues: []string{"develop"}, Val: float64(1)},
+		},
+		val)
+}
+
+func (suite *SkycoinStatsSuit) TestMetricSeqTimestamp() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var tomlConfig = `
+	# Service configuration.
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = 8080
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "localhost"
+
+		# All metrics to be measured.
+		[[services.metrics]]
+			name = "seq"
+			url = "/api/v1/health"
+			httpMethod = "GET"
+			path = "/blockchain/head/seq"
+			timestampPath = "/blockchain/head/timestamp"
+			timestampUnit = "s"
+
+			[services.metrics.options]
+				type = "Counter"
+				description = "Last block sequence"
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	conf := config.Config()
+	require.Len(conf.Services, 1)
+	require.Len(conf.Services[0].Metrics, 1)
+	mc, err := NewMetricClient(conf.Services[0].Metrics[0], conf.Services[0])
+	require.Nil(err, "Can not crate the metric")
+	var jsonData interface{}
+	jsonData, err = mc.DataClient().GetData(context.Background())
+	require.Nil(err, "Can not get the data")
+
+	// NOTE(denisacostaq@gmail.com): When
+	var timestamp time.Time
+	timestamp, err = mc.TimestampFromData(jsonData)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(mc.HasTimestamp())
+	suite.Nil(err)
+	suite.Equal(int64(1537581604), timestamp.Unix())
+}
+
+func (suite *SkycoinStatsSuit) TestMetricSeqTimestampFromNonTimeField() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var tomlConfig = `
+	# Service configuration.
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = 8080
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "localhost"
+
+		# All metrics to be measured.
+		[[services.metrics]]
+			name = "seq"
+			url = "/api/v1/health"
+			httpMethod = "GET"
+			path = "/blockchain/head/seq"
+			timestampPath = "/version/branch"
+
+			[services.metrics.options]
+				type = "Counter"
+				description = "Last block sequence"
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	conf := config.Config()
+	require.Len(conf.Services, 1)
+	require.Len(conf.Services[0].Metrics, 1)
+	mc, err := NewMetricClient(conf.Services[0].Metrics[0], conf.Services[0])
+	require.Nil(err, "Can not crate the metric")
+	var jsonData interface{}
+	jsonData, err = mc.DataClient().GetData(context.Background())
+	require.Nil(err, "Can not get the data")
+	timeData := map[string]interface{}{
+		"version": map[string]interface{}{"branch": "2018-09-22T02:00:04Z"},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	_, firstErr := mc.TimestampFromData(jsonData)
+	_, laterErr := mc.TimestampFromData(timeData)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotNil(firstErr)
+	suite.NotNil(laterErr)
+}
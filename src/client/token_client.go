@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -29,14 +30,15 @@ func newTokenClient(service config.Service) (client *TokenClient, err error) {
 	return client, nil
 }
 
-func (client *TokenClient) getRemoteInfo() (data []byte, err error) {
+func (client *TokenClient) getRemoteInfo(ctx context.Context) (data []byte, err error) {
 	const generalScopeErr = "error making a server request to get token from remote endpoint"
 	httpClient := &http.Client{}
 	var resp *http.Response
-	if resp, err = httpClient.Do(client.req); err != nil {
+	if resp, err = httpClient.Do(client.req.WithContext(ctx)); err != nil {
 		errCause := fmt.Sprintln("can not do the request: ", err.Error())
 		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
+	defer resp.Body.Close()
 	if data, err = ioutil.ReadAll(resp.Body); err != nil {
 		errCause := fmt.Sprintln("can not read the body: ", err.Error())
 		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
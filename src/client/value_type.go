@@ -0,0 +1,124 @@
+package client
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/simelo/rextporter/src/config"
+)
+
+// isoDurationRegexp match ISO 8601 durations with weeks, days, hours, minutes and seconds, like "P1DT2H3.5S",
+// years and months are not supported because they have not a fixed length.
+var isoDurationRegexp = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)W)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration returns the seconds in an ISO 8601 duration like "PT4M46S".
+func parseISODuration(str string) (seconds float64, err error) {
+	matches := isoDurationRegexp.FindStringSubmatch(str)
+	if matches == nil || str == "P" || str[len(str)-1] == 'T' {
+		return 0, fmt.Errorf("%s is not a supported ISO 8601 duration", str)
+	}
+	unitSeconds := []float64{7 * 24 * 3600, 24 * 3600, 3600, 60, 1}
+	for idxUnit, match := range matches[1:] {
+		if len(match) == 0 {
+			continue
+		}
+		var amount float64
+		if amount, err = strconv.ParseFloat(match, 64); err != nil {
+			return 0, err
+		}
+		seconds += amount * unitSeconds[idxUnit]
+	}
+	return seconds, nil
+}
+
+// durationSeconds returns the seconds in a Go duration like "6m30.6s" or in an ISO 8601 one like "PT6M30.6S".
+func durationSeconds(str string) (seconds float64, err error) {
+	var duration time.Duration
+	if duration, err = time.ParseDuration(str); err == nil {
+		return duration.Seconds(), nil
+	}
+	return parseISODuration(str)
+}
+
+// coerceValue convert val to a number according to valueType, arrays are converted item by item.
+// The unix times are in timestampUnit, sa parseTimestamp.
+func coerceValue(valueType, timestampUnit string, val interface{}) (number interface{}, err error) {
+	if items, ok := val.([]interface{}); ok {
+		numbers := make([]interface{}, len(items))
+		for idxItem, item := range items {
+			if numbers[idxItem], err = coerceValue(valueType, timestampUnit, item); err != nil {
+				return nil, err
+			}
+		}
+		return numbers, nil
+	}
+	switch valueType {
+	case config.KeyValueTypeBool:
+		switch typedVal := val.(type) {
+		case bool:
+			if typedVal {
+				return float64(1), nil
+			}
+			return float64(0), nil
+		case string:
+			var b bool
+			if b, err = strconv.ParseBool(typedVal); err != nil {
+				return nil, fmt.Errorf("unable to get value(%v) as bool: %s", val, err.Error())
+			}
+			return coerceValue(valueType, timestampUnit, b)
+		}
+		return nil, fmt.Errorf("unable to get value(%v) as bool", val)
+	case config.KeyValueTypeNumericString:
+		switch typedVal := val.(type) {
+		case float64:
+			return typedVal, nil
+		case string:
+			var f float64
+			if f, err = strconv.ParseFloat(typedVal, 64); err != nil {
+				return nil, fmt.Errorf("unable to get value(%v) as a number: %s", val, err.Error())
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("unable to get value(%v) as a numeric string", val)
+	case config.KeyValueTypeDuration:
+		switch typedVal := val.(type) {
+		case float64:
+			return typedVal, nil
+		case string:
+			var seconds float64
+			if seconds, err = durationSeconds(typedVal); err != nil {
+				return nil, fmt.Errorf("unable to get value(%v) as duration: %s", val, err.Error())
+			}
+			return seconds, nil
+		}
+		return nil, fmt.Errorf("unable to get value(%v) as duration", val)
+	case config.KeyValueTypeTimestamp:
+		var t time.Time
+		if t, err = parseTimestamp(val, timestampUnit); err != nil {
+			return nil, err
+		}
+		return float64(t.UnixNano()) / 1e9, nil
+	}
+	return val, nil
+}
+
+// parseTimestamp returns the time in val, a RFC3339 string or a unix time in unit, sa config.Metric.TimestampUnit.
+func parseTimestamp(val interface{}, unit string) (timestamp time.Time, err error) {
+	switch typedVal := val.(type) {
+	case float64:
+		if unit == config.KeyTimestampUnitMilliseconds {
+			typedVal /= 1e3
+		}
+		seconds, fraction := math.Modf(typedVal)
+		return time.Unix(int64(seconds), int64(fraction*1e9)), nil
+	case string:
+		if timestamp, err = time.Parse(time.RFC3339Nano, typedVal); err != nil {
+			return timestamp, fmt.Errorf("unable to get value(%v) as RFC3339 time: %s", val, err.Error())
+		}
+		return timestamp, nil
+	}
+	return timestamp, fmt.Errorf("unable to get value(%v) as a time", val)
+}
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/suite"
+)
+
+type valueTypeSuit struct {
+	suite.Suite
+}
+
+func TestValueTypeSuit(t *testing.T) {
+	suite.Run(t, new(valueTypeSuit))
+}
+
+func (suite *valueTypeSuit) TestBool() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	valueType := config.KeyValueTypeBool
+
+	// NOTE(denisacostaq@gmail.com): When
+	trueVal, trueErr := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, true)
+	falseVal, falseErr := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, "false")
+	_, invalidErr := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, float64(1))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(trueErr)
+	suite.Equal(float64(1), trueVal)
+	suite.Nil(falseErr)
+	suite.Equal(float64(0), falseVal)
+	suite.NotNil(invalidErr)
+}
+
+func (suite *valueTypeSuit) TestNumericString() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	valueType := config.KeyValueTypeNumericString
+
+	// NOTE(denisacostaq@gmail.com): When
+	val, err := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, "3.5")
+	_, invalidErr := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, "0.24.1")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal(3.5, val)
+	suite.NotNil(invalidErr)
+}
+
+func (suite *valueTypeSuit) TestDuration() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	valueType := config.KeyValueTypeDuration
+	durations := map[string]float64{
+		"4m46s":     286,
+		"6m30.6s":   390.6,
+		"PT4M46S":   286,
+		"P1DT1H":    90000,
+		"P1W":       604800,
+		"PT0.5S":    0.5,
+		"PT1H30M":   5400,
+		"P2D":       172800,
+		"PT6M30.6S": 390.6,
+	}
+
+	for str, seconds := range durations {
+		// NOTE(denisacostaq@gmail.com): When
+		val, err := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, str)
+
+		// NOTE(denisacostaq@gmail.com): Assert
+		suite.Nil(err, str)
+		suite.InDelta(seconds, val, 1e-9, str)
+	}
+	for _, str := range []string{"P", "PT", "P1Y", "P1M", "4 minutes"} {
+		_, err := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, str)
+		suite.NotNil(err, str)
+	}
+}
+
+func (suite *valueTypeSuit) TestTimestamp() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	valueType := config.KeyValueTypeTimestamp
+
+	// NOTE(denisacostaq@gmail.com): When
+	fromRFC3339, rfcErr := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, "2018-09-22T02:00:04.5Z")
+	fromUnixMs, msErr := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, float64(1537581604500))
+	_, invalidErr := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, "22/09/2018")
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(rfcErr)
+	suite.Equal(1537581604.5, fromRFC3339)
+	suite.Nil(msErr)
+	suite.Equal(1537581604.5, fromUnixMs)
+	suite.NotNil(invalidErr)
+}
+
+func (suite *valueTypeSuit) TestParseTimestamp() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	expected := time.Unix(1537581604, 5e8)
+
+	// NOTE(denisacostaq@gmail.com): When
+	fromRFC3339, rfcErr := parseTimestamp("2018-09-22T02:00:04.5Z", config.KeyTimestampUnitSeconds)
+	fromUnix, unixErr := parseTimestamp(1537581604.5, config.KeyTimestampUnitSeconds)
+	fromUnixMs, msErr := parseTimestamp(float64(1537581604500), config.KeyTimestampUnitMilliseconds)
+	_, invalidErr := parseTimestamp("develop", config.KeyTimestampUnitSeconds)
+	_, boolErr := parseTimestamp(true, config.KeyTimestampUnitSeconds)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(rfcErr)
+	suite.True(expected.Equal(fromRFC3339))
+	suite.Nil(unixErr)
+	suite.True(expected.Equal(fromUnix))
+	suite.Nil(msErr)
+	suite.True(expected.Equal(fromUnixMs))
+	suite.NotNil(invalidErr)
+	suite.NotNil(boolErr)
+}
+
+func (suite *valueTypeSuit) TestArray() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	valueType := config.KeyValueTypeBool
+
+	// NOTE(denisacostaq@gmail.com): When
+	val, err := coerceValue(valueType, config.KeyTimestampUnitMilliseconds, []interface{}{true, false, true})
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal([]interface{}{float64(1), float64(0), float64(1)}, val)
+}
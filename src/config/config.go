@@ -3,10 +3,23 @@ package config
 import (
 	"bytes"
 	"container/list"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
 
+	"github.com/shibukawa/configdir"
+	"github.com/simelo/rextporter/src/expression"
+	"github.com/simelo/rextporter/src/relabel"
+	"github.com/simelo/rextporter/src/selfmetrics"
 	"github.com/simelo/rextporter/src/util"
+	"github.com/simelo/rextporter/src/util/file"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
@@ -14,9 +27,471 @@ import (
 // RootConfig is the top level node for the config tree, it has a list of metrics and a
 // service from which get this metrics.
 type RootConfig struct {
+	// Namespace, Subsystem and ServiceLabels are the default ones for all the services,
+	// sa the Service type.
+	Namespace     string `json:"namespace"`
+	Subsystem     string `json:"subsystem"`
+	ServiceLabels bool   `json:"serviceLabels"`
+
+	// RelabelConfigs are Prometheus style relabel rules applied to all the metrics after the ones in each
+	// service, sa relabel.Config.
+	RelabelConfigs []relabel.Config `json:"relabelConfigs"`
+
+	// State allows you to keep the metrics state across the rextporter restarts.
+	State StateOptions `json:"state"`
+
+	// Push allows you to push the metrics to a Prometheus Pushgateway, sa PushOptions.
+	Push PushOptions `json:"push"`
+
+	// RemoteWrite allows you to send the metrics to a Prometheus remote write endpoint, sa RemoteWriteOptions.
+	RemoteWrite RemoteWriteOptions `json:"remoteWrite"`
+
+	// Sinks allows you to send the metrics to Graphite or StatsD, sa SinkOptions.
+	Sinks []SinkOptions `json:"sinks"`
+
+	// OTLP allows you to send the metrics to an OpenTelemetry collector, sa OTLPOptions.
+	OTLP OTLPOptions `json:"otlp"`
+
 	Services []Service `json:"services"`
 }
 
+// defaultStateInterval is how often the metrics state is saved if it is not defined.
+const defaultStateInterval = time.Minute
+
+// StateOptions allows you to persist the metrics state(the last success values and the accumulated counters)
+// in a file, so it is restored after a rextporter restart.
+type StateOptions struct {
+	Persist bool `json:"persist"`
+
+	// File is where the state is saved, by default "state.json" under the config folder.
+	File string `json:"file"`
+
+	// Interval is how often the state is saved(it is saved at shutdown too), by default each minute.
+	Interval time.Duration `json:"interval"`
+}
+
+// IntervalOrDefault returns how often the state should be saved.
+func (so StateOptions) IntervalOrDefault() time.Duration {
+	if so.Interval == 0 {
+		return defaultStateInterval
+	}
+	return so.Interval
+}
+
+// FilePath returns where the state should be saved, under the config folder if File is not defined.
+func (so StateOptions) FilePath() (path string, err error) {
+	const generalScopeErr = "can not get the state file path"
+	if len(so.File) != 0 {
+		return so.File, nil
+	}
+	var homeConf *configdir.Config
+	if homeConf, err = file.HomeConfigFolder(systemVendorName, systemProgramName); err != nil {
+		errCause := "error looking for config folder under home: " + err.Error()
+		return "", util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return file.DefaultConfigPath(stateFileName, homeConf), nil
+}
+
+func (so StateOptions) validate() (errs []error) {
+	if so.Interval < 0 {
+		errs = append(errs, errors.New("the state interval should not be negative"))
+	}
+	if !so.Persist && (len(so.File) != 0 || so.Interval != 0) {
+		errs = append(errs, errors.New("the state file and interval only apply if the state is persisted"))
+	}
+	return errs
+}
+
+const (
+	// defaultPushJob is the job in the push grouping key if it is not defined.
+	defaultPushJob = "rextporter"
+	// defaultPushInterval is how often the metrics are pushed if it is not defined.
+	defaultPushInterval = 15 * time.Second
+	// defaultPushTimeout is how long a push can take if it is not defined.
+	defaultPushTimeout = 10 * time.Second
+)
+
+// PushOptions allows you to push the metrics to a Prometheus Pushgateway each interval, useful if the
+// rextporter can not be scraped(for example behind a NAT). The metrics for each service are pushed in
+// his own group, with the job, the service name and the GroupingKey labels as grouping key.
+type PushOptions struct {
+	// URL is the Pushgateway address like "http://pushgateway:9091", the metrics are pushed only if it is defined.
+	URL string `json:"url"`
+
+	// Job is the job in the grouping key, by default "rextporter".
+	Job string `json:"job"`
+
+	// GroupingKey has labels to be added to the grouping key of all the services, for example
+	// groupingKey = { instance = "node1" }. The label names are used in lower case.
+	GroupingKey map[string]string `json:"groupingKey"`
+
+	// Interval is how often the metrics are pushed, by default each 15 seconds.
+	Interval time.Duration `json:"interval"`
+
+	// Timeout is how long the collection and each push request can take, by default 10 seconds.
+	Timeout time.Duration `json:"timeout"`
+
+	// Username and Password are the basic auth credentials for the Pushgateway, if any.
+	Username string `json:"username"`
+	Password string `json:"password"`
+
+	// DeleteOnShutdown says if the pushed groups should be deleted from the Pushgateway at shutdown.
+	DeleteOnShutdown bool `json:"deleteOnShutdown"`
+}
+
+// IsEnabled returns true if the metrics should be pushed.
+func (po PushOptions) IsEnabled() bool {
+	return len(po.URL) != 0
+}
+
+// JobOrDefault returns the job for the grouping key.
+func (po PushOptions) JobOrDefault() string {
+	if len(po.Job) == 0 {
+		return defaultPushJob
+	}
+	return po.Job
+}
+
+// IntervalOrDefault returns how often the metrics should be pushed.
+func (po PushOptions) IntervalOrDefault() time.Duration {
+	if po.Interval == 0 {
+		return defaultPushInterval
+	}
+	return po.Interval
+}
+
+// TimeoutOrDefault returns how long the collection and each push request can take.
+func (po PushOptions) TimeoutOrDefault() time.Duration {
+	if po.Timeout == 0 {
+		return defaultPushTimeout
+	}
+	return po.Timeout
+}
+
+func (po PushOptions) validate() (errs []error) {
+	if !po.IsEnabled() {
+		if len(po.Job) != 0 || len(po.GroupingKey) != 0 || po.Interval != 0 || po.Timeout != 0 ||
+			len(po.Username) != 0 || len(po.Password) != 0 || po.DeleteOnShutdown {
+			errs = append(errs, errors.New("the push options only apply if the push url is defined"))
+		}
+		return errs
+	}
+	if pushURL, err := url.Parse(po.URL); err != nil {
+		errs = append(errs, fmt.Errorf("push url %s is not valid: %s", po.URL, err.Error()))
+	} else if (pushURL.Scheme != "http" && pushURL.Scheme != "https") || len(pushURL.Host) == 0 {
+		errs = append(errs, fmt.Errorf("push url %s should be like http://host:port", po.URL))
+	}
+	if po.Interval < 0 || po.Timeout < 0 {
+		errs = append(errs, errors.New("the push interval and timeout should not be negative"))
+	}
+	if len(po.Password) != 0 && len(po.Username) == 0 {
+		errs = append(errs, errors.New("the push password requires an username"))
+	}
+	for name := range po.GroupingKey {
+		errs = append(errs, validateLabelName(name)...)
+		if name == "job" || name == KeyLabelService {
+			errs = append(errs, fmt.Errorf("label %s is already in the push grouping key", name))
+		}
+	}
+	return errs
+}
+
+const (
+	// defaultRemoteWriteInterval is how often the metrics are collected to be sent if it is not defined.
+	defaultRemoteWriteInterval = 15 * time.Second
+	// defaultRemoteWriteTimeout is how long the collection and each send can take if it is not defined.
+	defaultRemoteWriteTimeout = 10 * time.Second
+	// defaultRemoteWriteBatchSize is the max number of samples in a request if it is not defined.
+	defaultRemoteWriteBatchSize = 500
+	// defaultRemoteWriteQueueSize is the max number of samples waiting to be sent if it is not defined.
+	defaultRemoteWriteQueueSize = 10000
+	// defaultRemoteWriteMaxRetries is how many times a failed request is retried if it is not defined.
+	defaultRemoteWriteMaxRetries = 3
+	// defaultRemoteWriteRetryBackoff is the wait before the first retry if it is not defined.
+	defaultRemoteWriteRetryBackoff = time.Second
+)
+
+// RemoteWriteOptions allows you to send the metrics to a Prometheus remote write endpoint(for example a
+// central TSDB) each interval, without being scraped. The samples are queued in memory and sent in batches,
+// if the queue is full the new samples are dropped.
+type RemoteWriteOptions struct {
+	// URL is the remote write endpoint like "http://prometheus:9090/api/v1/write", the metrics are sent
+	// only if it is defined.
+	URL string `json:"url"`
+
+	// Interval is how often the metrics are collected to be sent, by default each 15 seconds.
+	Interval time.Duration `json:"interval"`
+
+	// Timeout is how long the collection and each request can take, by default 10 seconds.
+	Timeout time.Duration `json:"timeout"`
+
+	// BatchSize is the max number of samples in a request, by default 500.
+	BatchSize int `json:"batchSize"`
+
+	// QueueSize is the max number of samples waiting to be sent, by default 10000.
+	QueueSize int `json:"queueSize"`
+
+	// MaxRetries is how many times a request who fail with a recoverable error(a connection error,
+	// a 5xx or a 429 status code) is retried, by default 3.
+	MaxRetries int `json:"maxRetries"`
+
+	// RetryBackoff is the wait before the first retry, it is doubled for each next one, by default 1 second.
+	RetryBackoff time.Duration `json:"retryBackoff"`
+}
+
+// IsEnabled returns true if the metrics should be sent.
+func (rwo RemoteWriteOptions) IsEnabled() bool {
+	return len(rwo.URL) != 0
+}
+
+// IntervalOrDefault returns how often the metrics should be collected to be sent.
+func (rwo RemoteWriteOptions) IntervalOrDefault() time.Duration {
+	if rwo.Interval == 0 {
+		return defaultRemoteWriteInterval
+	}
+	return rwo.Interval
+}
+
+// TimeoutOrDefault returns how long the collection and each request can take.
+func (rwo RemoteWriteOptions) TimeoutOrDefault() time.Duration {
+	if rwo.Timeout == 0 {
+		return defaultRemoteWriteTimeout
+	}
+	return rwo.Timeout
+}
+
+// BatchSizeOrDefault returns the max number of samples in a request.
+func (rwo RemoteWriteOptions) BatchSizeOrDefault() int {
+	if rwo.BatchSize == 0 {
+		return defaultRemoteWriteBatchSize
+	}
+	return rwo.BatchSize
+}
+
+// QueueSizeOrDefault returns the max number of samples waiting to be sent.
+func (rwo RemoteWriteOptions) QueueSizeOrDefault() int {
+	if rwo.QueueSize == 0 {
+		return defaultRemoteWriteQueueSize
+	}
+	return rwo.QueueSize
+}
+
+// MaxRetriesOrDefault returns how many times a failed request should be retried.
+func (rwo RemoteWriteOptions) MaxRetriesOrDefault() int {
+	if rwo.MaxRetries == 0 {
+		return defaultRemoteWriteMaxRetries
+	}
+	return rwo.MaxRetries
+}
+
+// RetryBackoffOrDefault returns the wait before the first retry.
+func (rwo RemoteWriteOptions) RetryBackoffOrDefault() time.Duration {
+	if rwo.RetryBackoff == 0 {
+		return defaultRemoteWriteRetryBackoff
+	}
+	return rwo.RetryBackoff
+}
+
+func (rwo RemoteWriteOptions) validate() (errs []error) {
+	if !rwo.IsEnabled() {
+		if rwo.Interval != 0 || rwo.Timeout != 0 || rwo.BatchSize != 0 || rwo.QueueSize != 0 ||
+			rwo.MaxRetries != 0 || rwo.RetryBackoff != 0 {
+			errs = append(errs, errors.New("the remote write options only apply if the remote write url is defined"))
+		}
+		return errs
+	}
+	if writeURL, err := url.Parse(rwo.URL); err != nil {
+		errs = append(errs, fmt.Errorf("remote write url %s is not valid: %s", rwo.URL, err.Error()))
+	} else if (writeURL.Scheme != "http" && writeURL.Scheme != "https") || len(writeURL.Host) == 0 {
+		errs = append(errs, fmt.Errorf("remote write url %s should be like http://host:port/path", rwo.URL))
+	}
+	if rwo.Interval < 0 || rwo.Timeout < 0 || rwo.RetryBackoff < 0 {
+		errs = append(errs, errors.New("the remote write interval, timeout and retry backoff should not be negative"))
+	}
+	if rwo.BatchSize < 0 || rwo.QueueSize < 0 || rwo.MaxRetries < 0 {
+		errs = append(errs, errors.New("the remote write batch size, queue size and max retries should not be negative"))
+	}
+	if rwo.QueueSizeOrDefault() < rwo.BatchSizeOrDefault() {
+		errs = append(errs, errors.New("the remote write queue size should not be lower than the batch size"))
+	}
+	return errs
+}
+
+const (
+	// KeySinkTypeGraphite send the metrics to Graphite with the plaintext protocol over TCP.
+	KeySinkTypeGraphite = "graphite"
+	// KeySinkTypeStatsd send the metrics to StatsD as gauges over UDP.
+	KeySinkTypeStatsd = "statsd"
+
+	// defaultSinkInterval is how often the metrics are sent to a sink if it is not defined.
+	defaultSinkInterval = 15 * time.Second
+	// defaultSinkTimeout is how long the collection and each send to a sink can take if it is not defined.
+	defaultSinkTimeout = 10 * time.Second
+	// defaultSinkPathTemplate is the path for each metric if it is not defined, like "skycoin.wallet.seq".
+	defaultSinkPathTemplate = "{{.Namespace}}.{{.Service}}.{{.Metric}}"
+)
+
+// SinkPath has the values to build the path for a metric in a sink with the path template.
+type SinkPath struct {
+	Namespace string
+	Service   string
+	Metric    string
+}
+
+// SinkOptions allows you to send the metrics each interval to a push based backend who does not speak
+// the Prometheus protocols, like Graphite or StatsD.
+type SinkOptions struct {
+	// Type is the sink protocol, "graphite" or "statsd".
+	Type string `json:"type"`
+
+	// Address is the sink host and port, like "graphite:2003" or "statsd:8125".
+	Address string `json:"address"`
+
+	// Interval is how often the metrics are sent, by default each 15 seconds.
+	Interval time.Duration `json:"interval"`
+
+	// Timeout is how long the collection and each send can take, by default 10 seconds.
+	Timeout time.Duration `json:"timeout"`
+
+	// PathTemplate is a Go template for the metric path with the Namespace, Service and Metric values, by
+	// default "{{.Namespace}}.{{.Service}}.{{.Metric}}". The metric labels are added at the end like
+	// ".<label name>.<label value>".
+	PathTemplate string `json:"pathTemplate"`
+}
+
+// IntervalOrDefault returns how often the metrics should be sent.
+func (so SinkOptions) IntervalOrDefault() time.Duration {
+	if so.Interval == 0 {
+		return defaultSinkInterval
+	}
+	return so.Interval
+}
+
+// TimeoutOrDefault returns how long the collection and each send can take.
+func (so SinkOptions) TimeoutOrDefault() time.Duration {
+	if so.Timeout == 0 {
+		return defaultSinkTimeout
+	}
+	return so.Timeout
+}
+
+// PathTemplateOrDefault returns the template for the metric paths.
+func (so SinkOptions) PathTemplateOrDefault() string {
+	if len(so.PathTemplate) == 0 {
+		return defaultSinkPathTemplate
+	}
+	return so.PathTemplate
+}
+
+// ParsePathTemplate returns the template for the metric paths ready to be executed with a SinkPath.
+func (so SinkOptions) ParsePathTemplate() (*template.Template, error) {
+	return template.New("path").Option("missingkey=error").Parse(so.PathTemplateOrDefault())
+}
+
+func (so SinkOptions) validate() (errs []error) {
+	if so.Type != KeySinkTypeGraphite && so.Type != KeySinkTypeStatsd {
+		errs = append(errs, fmt.Errorf("sink type %s is not valid, it should be %s or %s", so.Type, KeySinkTypeGraphite, KeySinkTypeStatsd))
+	}
+	if _, _, err := net.SplitHostPort(so.Address); err != nil {
+		errs = append(errs, fmt.Errorf("sink address %s should be like host:port: %s", so.Address, err.Error()))
+	}
+	if so.Interval < 0 || so.Timeout < 0 {
+		errs = append(errs, errors.New("the sink interval and timeout should not be negative"))
+	}
+	pathTemplate, err := so.ParsePathTemplate()
+	if err == nil {
+		err = pathTemplate.Execute(ioutil.Discard, SinkPath{})
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("sink path template %s is not valid: %s", so.PathTemplateOrDefault(), err.Error()))
+	}
+	return errs
+}
+
+const (
+	// KeyOTLPEncodingJSON send the OTLP requests in the protobuf json mapping.
+	KeyOTLPEncodingJSON = "json"
+	// KeyOTLPEncodingProtobuf send the OTLP requests in the protobuf binary format.
+	KeyOTLPEncodingProtobuf = "protobuf"
+
+	// defaultOTLPInterval is how often the metrics are sent to the OTLP endpoint if it is not defined.
+	defaultOTLPInterval = 15 * time.Second
+	// defaultOTLPTimeout is how long the collection and each OTLP request can take if it is not defined.
+	defaultOTLPTimeout = 10 * time.Second
+)
+
+// OTLPOptions allows you to send the metrics each interval to an OpenTelemetry collector with the OTLP/HTTP
+// protocol. The counters, gauges and histograms for each service are sent with the service name and location
+// as resource attributes.
+type OTLPOptions struct {
+	// URL is the OTLP metrics endpoint like "http://otel-collector:4318/v1/metrics", the metrics are sent
+	// only if it is defined.
+	URL string `json:"url"`
+
+	// Encoding is the request body format, "json" or "protobuf", by default "json".
+	Encoding string `json:"encoding"`
+
+	// Headers are added to each request, for example headers = { authorization = "Bearer secret" }.
+	// The header names are used in lower case.
+	Headers map[string]string `json:"headers"`
+
+	// Interval is how often the metrics are sent, by default each 15 seconds.
+	Interval time.Duration `json:"interval"`
+
+	// Timeout is how long the collection and each request can take, by default 10 seconds.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// IsEnabled returns true if the metrics should be sent.
+func (oo OTLPOptions) IsEnabled() bool {
+	return len(oo.URL) != 0
+}
+
+// EncodingOrDefault returns the request body format.
+func (oo OTLPOptions) EncodingOrDefault() string {
+	if len(oo.Encoding) == 0 {
+		return KeyOTLPEncodingJSON
+	}
+	return oo.Encoding
+}
+
+// IntervalOrDefault returns how often the metrics should be sent.
+func (oo OTLPOptions) IntervalOrDefault() time.Duration {
+	if oo.Interval == 0 {
+		return defaultOTLPInterval
+	}
+	return oo.Interval
+}
+
+// TimeoutOrDefault returns how long the collection and each request can take.
+func (oo OTLPOptions) TimeoutOrDefault() time.Duration {
+	if oo.Timeout == 0 {
+		return defaultOTLPTimeout
+	}
+	return oo.Timeout
+}
+
+func (oo OTLPOptions) validate() (errs []error) {
+	if !oo.IsEnabled() {
+		if len(oo.Encoding) != 0 || len(oo.Headers) != 0 || oo.Interval != 0 || oo.Timeout != 0 {
+			errs = append(errs, errors.New("the otlp options only apply if the otlp url is defined"))
+		}
+		return errs
+	}
+	if otlpURL, err := url.Parse(oo.URL); err != nil {
+		errs = append(errs, fmt.Errorf("otlp url %s is not valid: %s", oo.URL, err.Error()))
+	} else if (otlpURL.Scheme != "http" && otlpURL.Scheme != "https") || len(otlpURL.Host) == 0 {
+		errs = append(errs, fmt.Errorf("otlp url %s should be like http://host:port/v1/metrics", oo.URL))
+	}
+	if encoding := oo.EncodingOrDefault(); encoding != KeyOTLPEncodingJSON && encoding != KeyOTLPEncodingProtobuf {
+		errs = append(errs, fmt.Errorf("otlp encoding %s is not valid, it should be %s or %s", encoding, KeyOTLPEncodingJSON, KeyOTLPEncodingProtobuf))
+	}
+	if oo.Interval < 0 || oo.Timeout < 0 {
+		errs = append(errs, errors.New("the otlp interval and timeout should not be negative"))
+	}
+	return errs
+}
+
 var rootConfig RootConfig
 
 // Config TODO(denisacostaq@gmail.com): make a singleton
@@ -45,7 +520,9 @@ func NewConfigFromRawString(strConf string) error {
 		errCause := fmt.Sprintln("can not decode the config data: ", err.Error())
 		return util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
+	rootConfig.inheritOptions()
 	rootConfig.validate()
+	selfmetrics.ConfigLoaded(time.Now())
 	return nil
 }
 
@@ -102,11 +579,69 @@ func NewConfigFromFileSystem(mainConfigPath string) {
 		errCause := "error reading metrics config: " + err.Error()
 		panic(errCause)
 	}
+	if rootConfig, err = newRootConfigFromMainFile(conf.MainConfigPath()); err != nil {
+		errCause := "error reading main config: " + err.Error()
+		panic(util.ErrorFromThisScope(errCause, generalScopeErr))
+	}
 	if rootConfig.Services, err = newServiceConfigFromFile(conf.ServicesConfigPath(), conf); err != nil {
 		errCause := "root cause: " + err.Error()
 		panic(util.ErrorFromThisScope(errCause, generalScopeErr))
 	}
+	rootConfig.inheritOptions()
 	rootConfig.validate()
+	selfmetrics.ConfigLoaded(time.Now())
+}
+
+// newRootConfigFromMainFile desserialize the options in the main config file, without the services.
+func newRootConfigFromMainFile(path string) (conf RootConfig, err error) {
+	const generalScopeErr = "error reading main config"
+	viper.SetConfigFile(path)
+	viper.SetConfigType("toml")
+	if err = viper.ReadInConfig(); err != nil {
+		errCause := fmt.Sprintln("error reading config file: ", path, err.Error())
+		return conf, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	if err = viper.Unmarshal(&conf); err != nil {
+		errCause := fmt.Sprintln("can not decode the config data: ", err.Error())
+		return conf, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	conf.Services = nil
+	return conf, nil
+}
+
+// inheritOptions set the options in the main config to the services who does not define them.
+func (conf *RootConfig) inheritOptions() {
+	for idxService := range conf.Services {
+		srv := &conf.Services[idxService]
+		if len(srv.Namespace) == 0 {
+			srv.Namespace = conf.Namespace
+		}
+		if len(srv.Subsystem) == 0 {
+			srv.Subsystem = conf.Subsystem
+		}
+		if srv.ServiceLabels == nil {
+			serviceLabels := conf.ServiceLabels
+			srv.ServiceLabels = &serviceLabels
+		}
+	}
+}
+
+// NamespaceOrDefault returns the namespace in the main config or the default one if not defined.
+func (conf RootConfig) NamespaceOrDefault() string {
+	if len(conf.Namespace) == 0 {
+		return defaultNamespace
+	}
+	return conf.Namespace
+}
+
+// FindService returns the service with the giving name, ok is false if there is not such service.
+func (conf RootConfig) FindService(name string) (srv Service, ok bool) {
+	for _, srv = range conf.Services {
+		if srv.Name == name {
+			return srv, true
+		}
+	}
+	return Service{}, false
 }
 
 // FilterMetricsByType will return all the metrics who match whit the 't' parameter.
@@ -129,9 +664,30 @@ func (conf RootConfig) FilterMetricsByType(t string) (metrics []Metric) {
 }
 
 func (conf RootConfig) validate() {
-	var errs []error
+	errs := conf.State.validate()
+	errs = append(errs, conf.Push.validate()...)
+	errs = append(errs, conf.RemoteWrite.validate()...)
+	for _, sink := range conf.Sinks {
+		errs = append(errs, sink.validate()...)
+	}
+	errs = append(errs, conf.OTLP.validate()...)
+	if _, err := relabel.CompileAll(conf.RelabelConfigs); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, conf.validateSharedNames()...)
+	metricKeys := make(map[string]bool)
 	for _, service := range conf.Services {
 		errs = append(errs, service.validate()...)
+		for _, metric := range service.Metrics {
+			key := service.metricKey(metric)
+			if metricKeys[key] {
+				errs = append(errs, fmt.Errorf("metric %s is defined more than once", key))
+			}
+			metricKeys[key] = true
+			if metric.IsDerived() {
+				errs = append(errs, conf.validateDerived(service, metric)...)
+			}
+		}
 	}
 	if len(errs) != 0 {
 		defer log.Panicln("some errors found")
@@ -141,6 +697,98 @@ func (conf RootConfig) validate() {
 	}
 }
 
+// metricShape is what should be the same for all the metrics exposed with the same name, sa validateSharedNames.
+type metricShape struct {
+	service    string
+	help       string
+	metricType string
+	labelNames string
+}
+
+// validateSharedNames returns an error for each metric exposed with the same name(for example with the
+// service labels enabled) than a metric in other service but with a different help, type or label names,
+// they can not be exposed together.
+func (conf RootConfig) validateSharedNames() (errs []error) {
+	shapes := make(map[string]metricShape)
+	for _, service := range conf.Services {
+		for _, metric := range service.Metrics {
+			labelNames := metric.LabelNames()
+			for name := range service.MetricConstLabels() {
+				labelNames = append(labelNames, name)
+			}
+			sort.Strings(labelNames)
+			shape := metricShape{
+				service:    service.Name,
+				help:       metric.Options.Description,
+				metricType: metric.Options.Type,
+				labelNames: strings.Join(labelNames, ","),
+			}
+			name := service.ExposedMetricName(metric)
+			other, ok := shapes[name]
+			if !ok {
+				shapes[name] = shape
+				continue
+			}
+			if other.help != shape.help || other.metricType != shape.metricType || other.labelNames != shape.labelNames {
+				errs = append(errs, fmt.Errorf(
+					"metric %s in service %s should have the same description, type and label names(%s) than in service %s(%s)",
+					name, shape.service, shape.labelNames, other.service, other.labelNames))
+			}
+		}
+	}
+	return errs
+}
+
+// MetricSource is a metric referred in the expression of a derived metric, with the service where it is defined.
+type MetricSource struct {
+	Service Service
+	Metric  Metric
+}
+
+// DerivedSources returns the metrics referred by ref in the expression of a derived metric in srv. If
+// ref is aggregated the service can be a pattern and all the services who match it are included.
+func (conf RootConfig) DerivedSources(srv Service, ref expression.Ref) (sources []MetricSource) {
+	for _, refSrv := range conf.Services {
+		if len(ref.Service) == 0 {
+			if refSrv.Name != srv.Name {
+				continue
+			}
+		} else if matched, err := path.Match(ref.Service, refSrv.Name); err != nil || !matched {
+			continue
+		}
+		for _, metric := range refSrv.Metrics {
+			if metric.Name == ref.Metric {
+				sources = append(sources, MetricSource{Service: refSrv, Metric: metric})
+			}
+		}
+	}
+	return sources
+}
+
+// validateDerived check that the metrics in the expression of a derived metric in srv exist and they are counters
+// or gauges, out of an aggregation they should not have labels too.
+func (conf RootConfig) validateDerived(srv Service, metric Metric) (errs []error) {
+	expr, err := expression.Parse(metric.Expression)
+	if err != nil {
+		// NOTE(denisacostaq@gmail.com): reported in the metric validation
+		return nil
+	}
+	for _, ref := range expr.Refs() {
+		sources := conf.DerivedSources(srv, ref)
+		if len(sources) == 0 {
+			errs = append(errs, fmt.Errorf("metric %s in the expression of %s is not defined", ref, metric.Name))
+		}
+		for _, source := range sources {
+			if source.Metric.Options.Type != KeyTypeCounter && source.Metric.Options.Type != KeyTypeGauge {
+				errs = append(errs, fmt.Errorf("metric %s in the expression of %s should be a counter or a gauge", ref, metric.Name))
+			} else if !ref.Aggregated && len(source.Metric.LabelNames()) != 0 {
+				errs = append(errs, fmt.Errorf("metric %s in the expression of %s has labels, it should be aggregated", ref, metric.Name))
+			}
+		}
+	}
+	return errs
+}
+
 // isValidUrl tests a string to determine if it is a valid URL or not.
 func isValidURL(toTest string) bool {
 	if _, err := url.ParseRequestURI(toTest); err != nil {
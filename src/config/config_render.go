@@ -52,6 +52,65 @@ serviceConfigTransport = "file" # "file" | "consulCatalog"
 # render a template with a portable path
 servicesConfigPath = "{{.ServicesConfigPath}}"
 metricsForServicesPath = "{{.MetricsForServicesPath}}"
+# the metrics are named like namespace_subsystem_name, by default the namespace is "skycoin"
+# and the subsystem is the service name, they can be overwritten in each service and metric.
+# namespace = "skycoin"
+# subsystem = ""
+# expose the service name and location as the "service" and "location" labels instead of
+# using the service name as subsystem, so the same metric from many services can be aggregated.
+# serviceLabels = false
+# keep the metrics state(the last success values and the accumulated counters) across restarts
+# [state]
+#   persist = true
+#   # by default state.json under the config folder
+#   file = "/var/lib/rextporter/state.json"
+#   interval = "1m"
+# push the metrics for each service to a Prometheus Pushgateway, in the group
+# job/<job>/service/<service name> plus the groupingKey labels
+# [push]
+#   url = "http://pushgateway:9091"
+#   job = "rextporter"
+#   groupingKey = { instance = "node1" }
+#   interval = "15s"
+#   timeout = "10s"
+#   username = "rextporter"
+#   password = "secret"
+#   deleteOnShutdown = true
+# send the metrics to a Prometheus remote write endpoint, the samples are queued in memory
+# and sent in batches, the failed requests are retried with an exponential backoff
+# [remoteWrite]
+#   url = "http://prometheus:9090/api/v1/write"
+#   interval = "15s"
+#   timeout = "10s"
+#   batchSize = 500
+#   queueSize = 10000
+#   maxRetries = 3
+#   retryBackoff = "1s"
+# send the metrics to Graphite(plaintext over TCP) or StatsD(gauges over UDP) each interval, the
+# path template can use the Namespace, Service and Metric values and the labels are added at the end
+# [[sinks]]
+#   type = "graphite"
+#   address = "graphite:2003"
+#   interval = "15s"
+#   timeout = "10s"
+#   pathTemplate = "{{"{{.Namespace}}.{{.Service}}.{{.Metric}}"}}"
+# send the counters, gauges and histograms to an OpenTelemetry collector with OTLP/HTTP, the service
+# name and location are sent as resource attributes
+# [otlp]
+#   url = "http://otel-collector:4318/v1/metrics"
+#   encoding = "json"
+#   headers = { authorization = "Bearer secret" }
+#   interval = "15s"
+#   timeout = "10s"
+# Prometheus style relabel rules(replace, keep, drop, labeldrop, labelmap or hashmod) for all the
+# metrics, applied after the rules in each service, the metric name is in the "__name__" label
+# [[relabelConfigs]]
+#   sourceLabels = ["__name__"]
+#   regex = ".*_up"
+#   action = "drop"
+# [[relabelConfigs]]
+#   targetLabel = "env"
+#   replacement = "production"
 `
 
 const serviceConfigFileContentTemplate = `
@@ -65,6 +124,17 @@ const serviceConfigFileContentTemplate = `
   tokenHeaderKey = "X-CSRF-Token"
   genTokenEndpoint = "/api/v1/csrf"
   tokenKeyFromEndpoint = "csrf_token"
+  # poll the service in background instead of in each scrape
+  # pollInterval = "15s"
+  # labels with a fixed value for all the metrics in this service
+  # constLabels = { network = "mainnet" }
+  # serviceLabels = true
+  # relabel rules for the metrics in this service
+  # [[services.relabelConfigs]]
+  #   sourceLabels = ["__name__"]
+  #   regex = "skycoin_wallet1_(.*)"
+  #   targetLabel = "__name__"
+  #   replacement = "wallet_${1}"
 
   [services.location]
     location = "localhost"
@@ -76,11 +146,19 @@ const skycoinMetricsConfigFileContentTemplate = `
   url = "/api/v1/health"
   httpMethod = "GET"
   path = "/blockchain/head/seq"
+  # expose the samples with the block time(RFC3339 or unix time) instead of the scrape time
+  # timestampPath = "/blockchain/head/timestamp"
+  # unit for the unix times in timestampPath or in a "timestamp" valueType: "ms"(default) or "s"
+  # timestampUnit = "s"
 
   [metrics.options]
     type = "Counter"
     description = "I am running since"
 
+  # [metrics.counterOptions]
+  #   # accumulate the upstream increases, so the counter never goes down if the node is restarted
+  #   accumulate = true
+
 # [[metrics]]
 #   name = "openConnections"
 #   url = "/api/v1/network/connections"
@@ -98,6 +176,107 @@ const skycoinMetricsConfigFileContentTemplate = `
 #       3
 #     ]
 
+# [[metrics]]
+#   name = "fee"
+#   url = "/api/v1/health"
+#   httpMethod = "GET"
+#   path = "/blockchain/head/fee"
+
+#   [metrics.options]
+#     type = "Summary"
+#     description = "Fee distribution"
+
+#   [metrics.summaryOptions]
+#     objectives = [
+#       { quantile = 0.5, error = 0.05 },
+#       { quantile = 0.9, error = 0.01 },
+#       { quantile = 0.99, error = 0.001 }
+#     ]
+#     maxAge = "10m"
+#     ageBuckets = 5
+
+# [[metrics]]
+#   name = "version"
+#   url = "/api/v1/health"
+#   httpMethod = "GET"
+#   # an info metric is exposed as "version_info" with a constant 1 value and the strings
+#   # found in the labels paths(relative to path) as label values
+#   path = "/version"
+#   labels = [
+#     { name = "version", path = "/version" },
+#     { name = "commit", path = "/commit" },
+#     { name = "branch", path = "/branch" }
+#   ]
+
+#   [metrics.options]
+#     type = "Info"
+#     description = "Node version"
+
+#   [metrics.infoOptions]
+#     # truncate the label values longer than this
+#     maxValueLength = 64
+
+# [[metrics]]
+#   name = "uptime"
+#   url = "/api/v1/health"
+#   httpMethod = "GET"
+#   path = "/uptime"
+#   # how to get a number from the value: "number"(default), "bool", "numericString",
+#   # "duration"(like "6m30s" or "PT6M30S") or "timestamp"(RFC3339 or unix time in timestampUnit)
+#   valueType = "duration"
+
+#   [metrics.options]
+#     type = "Gauge"
+#     description = "Uptime in seconds"
+
+# [[metrics]]
+#   name = "version_minor"
+#   url = "/api/v1/health"
+#   httpMethod = "GET"
+#   path = "/version/version"
+
+#   [metrics.options]
+#     type = "Gauge"
+#     description = "Minor version"
+
+#   # map the string values to numbers through a values table, a regex capture group or
+#   # states(one serie for each state, labeled with the metric name, only for gauges)
+#   [metrics.mappingOptions]
+#     regex = '^\d+\.(\d+)\.\d+$'
+#     # values = [{value = "synced", number = 1}, {value = "syncing", number = 0}]
+#     # states = ["master", "develop"]
+
+# [[metrics]]
+#   name = "unconfirmed_by_connection"
+#   # an arithmetic expression(+, -, *, /) over other counters or gauges in this service("metric"),
+#   # in other service("service.metric") or aggregated with sum, avg, min, max or count, the
+#   # service in an aggregation can be a pattern like "sum(wallet*.open_connections)"
+#   expression = "unconfirmed / open_connections"
+
+#   [metrics.options]
+#     type = "Derived"
+#     description = "Unconfirmed transactions by connection"
+
+# [[metrics]]
+#   name = "open_connection_height"
+#   url = "/api/v1/network/connections"
+#   httpMethod = "GET"
+#   path = "/connections"
+#   # where is the value inside each connection
+#   itemPath = "/height"
+#   # one serie per connection, use path = "#key" to get the key if the path is an object
+#   labels = [
+#     { name = "address", path = "/address" }
+#   ]
+
+#   [metrics.options]
+#     type = "Gauge"
+#     description = "Height reported by each connection"
+#     # what to expose if the metric can not be updated: "keep"(default), "drop" or "nan"
+#     stalePolicy = "keep"
+#     # stop exposing the last success values after this time
+#     staleMaxAge = "5m"
+
 
 
 
@@ -124,6 +303,7 @@ var (
 	skycoinMetricsConfigFileName     = "skycoinMetrics.toml"
 	walletMetricsConfigFileName      = "walletMetrics.toml"
 	metricsForServicesConfigFileName = "metricsForServices.toml"
+	stateFileName                    = "state.json"
 )
 
 func (confData mainConfigData) existServicesConfigFile() bool {
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/simelo/rextporter/src/expression"
+	"github.com/stretchr/testify/suite"
+)
+
+type rootConfSuite struct {
+	suite.Suite
+	RootConf RootConfig
+}
+
+func (suite *rootConfSuite) SetupTest() {
+	gauge := func(name string, labels ...Label) Metric {
+		return Metric{
+			Name:       name,
+			URL:        "/api/v1/health",
+			HTTPMethod: "GET",
+			Path:       "/" + name,
+			Labels:     labels,
+			Options:    MetricOptions{Type: KeyTypeGauge},
+		}
+	}
+	wallet := func(name string) Service {
+		return Service{
+			Name:     name,
+			Scheme:   "http",
+			Location: Server{Location: "localhost"},
+			Port:     8080,
+			Metrics: []Metric{
+				gauge("unconfirmed"),
+				gauge("open_connections"),
+				gauge("connection_height", Label{Name: "peer", Path: "/address"}),
+				Metric{
+					Name:       "uptime",
+					URL:        "/api/v1/health",
+					HTTPMethod: "GET",
+					Path:       "/uptime",
+					Options:    MetricOptions{Type: KeyTypeHistogram},
+				},
+			},
+		}
+	}
+	suite.RootConf = RootConfig{Services: []Service{wallet("wallet1"), wallet("wallet2"), wallet("node")}}
+}
+
+func TestRootConfSuite(t *testing.T) {
+	suite.Run(t, new(rootConfSuite))
+}
+
+func (suite *rootConfSuite) TestDerivedSources() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	srv := suite.RootConf.Services[0]
+
+	// NOTE(denisacostaq@gmail.com): When
+	sameService := suite.RootConf.DerivedSources(srv, expression.Ref{Metric: "unconfirmed"})
+	otherService := suite.RootConf.DerivedSources(srv, expression.Ref{Service: "wallet2", Metric: "unconfirmed"})
+	aggregated := suite.RootConf.DerivedSources(srv, expression.Ref{Service: "wallet*", Metric: "unconfirmed", Aggregated: true})
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(sameService, 1)
+	suite.Equal("wallet1", sameService[0].Service.Name)
+	suite.Len(otherService, 1)
+	suite.Equal("wallet2", otherService[0].Service.Name)
+	suite.Len(aggregated, 2)
+}
+
+func (suite *rootConfSuite) TestValidDerived() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	srv := suite.RootConf.Services[0]
+	metric := Metric{
+		Name:       "pending",
+		Expression: "unconfirmed / open_connections + sum(wallet*.open_connections) + max(connection_height)",
+		Options:    MetricOptions{Type: KeyTypeDerived},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	errs := suite.RootConf.validateDerived(srv, metric)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(errs, 0)
+}
+
+func (suite *rootConfSuite) TestInvalidDerived() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	srv := suite.RootConf.Services[0]
+	metric := Metric{
+		Name:       "pending",
+		Expression: "not_defined + sum(other*.unconfirmed) + uptime + connection_height",
+		Options:    MetricOptions{Type: KeyTypeDerived},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	errs := suite.RootConf.validateDerived(srv, metric)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(errs, 4)
+}
+
+func (suite *rootConfSuite) TestStateOptions() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	state := StateOptions{Persist: true, File: "/tmp/state.json"}
+
+	// NOTE(denisacostaq@gmail.com): When
+	path, err := state.FilePath()
+	validErrs := state.validate()
+	state.Interval = -time.Second
+	negativeErrs := state.validate()
+	notPersistedErrs := StateOptions{File: "/tmp/state.json"}.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal("/tmp/state.json", path)
+	suite.Equal(defaultStateInterval, StateOptions{Persist: true}.IntervalOrDefault())
+	suite.Len(validErrs, 0)
+	suite.Len(negativeErrs, 1)
+	suite.Len(notPersistedErrs, 1)
+}
+
+func (suite *rootConfSuite) TestPushOptions() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	push := PushOptions{
+		URL:         "http://127.0.0.1:9091",
+		GroupingKey: map[string]string{"instance": "node1"},
+		Username:    "rextporter",
+		Password:    "secret",
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	validErrs := push.validate()
+	invalidPush := push
+	invalidPush.URL = "127.0.0.1:9091"
+	invalidPush.Interval = -time.Second
+	invalidPush.Username = ""
+	invalidPush.GroupingKey = map[string]string{"service": "wallet", "in-stance": "node1"}
+	invalidErrs := invalidPush.validate()
+	notEnabledErrs := PushOptions{DeleteOnShutdown: true}.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(push.IsEnabled())
+	suite.Equal(defaultPushJob, push.JobOrDefault())
+	suite.Equal(defaultPushInterval, push.IntervalOrDefault())
+	suite.Equal(defaultPushTimeout, push.TimeoutOrDefault())
+	suite.Len(validErrs, 0)
+	suite.Len(invalidErrs, 5)
+	suite.False(PushOptions{}.IsEnabled())
+	suite.Len(notEnabledErrs, 1)
+}
+
+func (suite *rootConfSuite) TestRemoteWriteOptions() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	remoteWrite := RemoteWriteOptions{URL: "http://127.0.0.1:9090/api/v1/write", BatchSize: 100}
+
+	// NOTE(denisacostaq@gmail.com): When
+	validErrs := remoteWrite.validate()
+	invalidRemoteWrite := remoteWrite
+	invalidRemoteWrite.URL = "ftp://127.0.0.1/write"
+	invalidRemoteWrite.RetryBackoff = -time.Second
+	invalidRemoteWrite.MaxRetries = -1
+	invalidErrs := invalidRemoteWrite.validate()
+	smallQueueErrs := RemoteWriteOptions{URL: remoteWrite.URL, QueueSize: 10}.validate()
+	notEnabledErrs := RemoteWriteOptions{BatchSize: 10}.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(remoteWrite.IsEnabled())
+	suite.Equal(100, remoteWrite.BatchSizeOrDefault())
+	suite.Equal(defaultRemoteWriteQueueSize, remoteWrite.QueueSizeOrDefault())
+	suite.Equal(defaultRemoteWriteMaxRetries, remoteWrite.MaxRetriesOrDefault())
+	suite.Equal(defaultRemoteWriteRetryBackoff, remoteWrite.RetryBackoffOrDefault())
+	suite.Len(validErrs, 0)
+	suite.Len(invalidErrs, 3)
+	suite.Len(smallQueueErrs, 1)
+	suite.Len(notEnabledErrs, 1)
+}
+
+func (suite *rootConfSuite) TestSinkOptions() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	sink := SinkOptions{Type: KeySinkTypeGraphite, Address: "127.0.0.1:2003"}
+	statsd := SinkOptions{Type: KeySinkTypeStatsd, Address: "127.0.0.1:8125", PathTemplate: "legacy.{{.Service}}.{{.Metric}}"}
+
+	// NOTE(denisacostaq@gmail.com): When
+	validErrs := sink.validate()
+	validStatsdErrs := statsd.validate()
+	invalidSink := sink
+	invalidSink.Type = "carbon"
+	invalidSink.Address = "127.0.0.1"
+	invalidSink.Timeout = -time.Second
+	invalidSink.PathTemplate = "{{.Namespace}}.{{.Host}}"
+	invalidErrs := invalidSink.validate()
+	malformedTemplateErrs := SinkOptions{Type: KeySinkTypeStatsd, Address: statsd.Address, PathTemplate: "{{.Metric"}.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(defaultSinkInterval, sink.IntervalOrDefault())
+	suite.Equal(defaultSinkTimeout, sink.TimeoutOrDefault())
+	suite.Equal(defaultSinkPathTemplate, sink.PathTemplateOrDefault())
+	suite.Len(validErrs, 0)
+	suite.Len(validStatsdErrs, 0)
+	suite.Len(invalidErrs, 4)
+	suite.Len(malformedTemplateErrs, 1)
+}
+
+func (suite *rootConfSuite) TestOTLPOptions() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	otlp := OTLPOptions{URL: "http://127.0.0.1:4318/v1/metrics", Headers: map[string]string{"authorization": "Bearer secret"}}
+
+	// NOTE(denisacostaq@gmail.com): When
+	validErrs := otlp.validate()
+	invalidOTLP := otlp
+	invalidOTLP.URL = "127.0.0.1:4318"
+	invalidOTLP.Encoding = "grpc"
+	invalidOTLP.Interval = -time.Second
+	invalidErrs := invalidOTLP.validate()
+	notEnabledErrs := OTLPOptions{Encoding: KeyOTLPEncodingProtobuf}.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(otlp.IsEnabled())
+	suite.Equal(KeyOTLPEncodingJSON, otlp.EncodingOrDefault())
+	suite.Equal(defaultOTLPInterval, otlp.IntervalOrDefault())
+	suite.Equal(defaultOTLPTimeout, otlp.TimeoutOrDefault())
+	suite.Len(validErrs, 0)
+	suite.Len(invalidErrs, 3)
+	suite.False(OTLPOptions{}.IsEnabled())
+	suite.Len(notEnabledErrs, 1)
+}
+
+func (suite *rootConfSuite) TestSharedMetricNames() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	serviceLabels := true
+	for idxService := range suite.RootConf.Services {
+		suite.RootConf.Services[idxService].ServiceLabels = &serviceLabels
+	}
+	withServiceNames := suite.RootConf
+	withServiceNames.Services = []Service{suite.RootConf.Services[0], suite.RootConf.Services[1]}
+	withServiceNames.Services[1].ServiceLabels = nil
+	otherHelp := suite.RootConf
+	otherHelp.Services = []Service{suite.RootConf.Services[0], suite.RootConf.Services[1]}
+	otherHelp.Services[1].Metrics = append([]Metric(nil), otherHelp.Services[1].Metrics...)
+	otherHelp.Services[1].Metrics[0].Options.Description = "Unconfirmed transactions"
+	otherConstLabels := suite.RootConf
+	otherConstLabels.Services = []Service{suite.RootConf.Services[0], suite.RootConf.Services[1]}
+	otherConstLabels.Services[1].ConstLabels = map[string]string{"network": "mainnet"}
+	infoAndGauge := suite.RootConf
+	infoAndGauge.Services = []Service{suite.RootConf.Services[0], suite.RootConf.Services[1]}
+	version := Metric{
+		Name:    "version",
+		URL:     "/api/v1/health",
+		Path:    "/version",
+		Labels:  []Label{Label{Name: "branch", Path: "/branch"}},
+		Options: MetricOptions{Type: KeyTypeInfo},
+	}
+	infoAndGauge.Services[0].Metrics = append([]Metric{version}, infoAndGauge.Services[0].Metrics...)
+	version.Name = "version_info"
+	version.Options.Type = KeyTypeGauge
+	infoAndGauge.Services[1].Metrics = append([]Metric{version}, infoAndGauge.Services[1].Metrics...)
+
+	// NOTE(denisacostaq@gmail.com): When
+	sharedErrs := suite.RootConf.validateSharedNames()
+	withServiceNamesErrs := withServiceNames.validateSharedNames()
+	otherHelpErrs := otherHelp.validateSharedNames()
+	otherConstLabelsErrs := otherConstLabels.validateSharedNames()
+	infoAndGaugeErrs := infoAndGauge.validateSharedNames()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(sharedErrs, 0)
+	suite.Len(withServiceNamesErrs, 0)
+	suite.Len(otherHelpErrs, 1)
+	suite.Len(otherConstLabelsErrs, 4)
+	suite.Len(infoAndGaugeErrs, 1)
+}
@@ -2,6 +2,14 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/simelo/rextporter/src/expression"
 )
 
 const (
@@ -13,16 +21,95 @@ const (
 	KeyTypeHistogram = "Histogram"
 	// KeyTypeSummary is the key you should define in the config file for summaries.
 	KeyTypeSummary = "Summary"
+	// KeyTypeInfo is the key you should define in the config file for info metrics, they are exposed as
+	// a gauge with a constant 1 value and the strings in the label paths as label values.
+	KeyTypeInfo = "Info"
+	// InfoMetricSuffix is appended to the name of the info metrics, sa Service.ExposedMetricName.
+	InfoMetricSuffix = "_info"
+	// KeyTypeDerived is the key you should define in the config file for derived metrics, they are exposed
+	// as a gauge with the value of an arithmetic expression over other metrics, sa Metric.Expression.
+	KeyTypeDerived = "Derived"
+	// KeyStalePolicyKeep is the stale policy to keep exposing the last success value for a metric who fail.
+	KeyStalePolicyKeep = "keep"
+	// KeyStalePolicyDrop is the stale policy to stop exposing a metric who fail.
+	KeyStalePolicyDrop = "drop"
+	// KeyStalePolicyNaN is the stale policy to expose a NaN value for a metric who fail.
+	KeyStalePolicyNaN = "nan"
+	// KeyLabelPathItemKey is the label path you should define in the config file to use the key
+	// of each item as label value when the metric path is an object(map).
+	KeyLabelPathItemKey = "#key"
+	// KeyValueTypeNumber is the value type for json numbers, it is the default one.
+	KeyValueTypeNumber = "number"
+	// KeyValueTypeBool is the value type for json booleans, exposed as 1 for true and 0 for false.
+	KeyValueTypeBool = "bool"
+	// KeyValueTypeNumericString is the value type for strings with a number like "3.14".
+	KeyValueTypeNumericString = "numericString"
+	// KeyValueTypeDuration is the value type for durations like "6m30.6s"(Go style) or "PT6M30.6S"(ISO 8601),
+	// exposed in seconds.
+	KeyValueTypeDuration = "duration"
+	// KeyValueTypeTimestamp is the value type for RFC3339 times or unix times(sa Metric.TimestampUnit),
+	// exposed as unix times in seconds.
+	KeyValueTypeTimestamp = "timestamp"
+	// KeyTimestampUnitMilliseconds is the timestamp unit for unix times in milliseconds, it is the default one.
+	KeyTimestampUnitMilliseconds = "ms"
+	// KeyTimestampUnitSeconds is the timestamp unit for unix times in seconds.
+	KeyTimestampUnitSeconds = "s"
 )
 
 // Metric keep the metric name as an instance of MetricOptions
 type Metric struct {
-	Name             string           `json:"name"`
-	URL              string           `json:"url"`
-	HTTPMethod       string           `json:"http_method"`
-	Path             string           `json:"path,omitempty"`
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	HTTPMethod string `json:"httpMethod"`
+	Path       string `json:"path,omitempty"`
+
+	// ItemPath is where to find the value inside each item of a labeled metric, if empty
+	// the item it self is the value.
+	ItemPath string `json:"itemPath,omitempty"`
+
+	// ValueType says how to convert the value found in the path(or item path) to a number, for example
+	// "bool" or "duration", sa the KeyValueType constants. If empty the value should be a json number.
+	ValueType string `json:"valueType"`
+
+	// TimestampPath is where to find the time for the metric samples(RFC3339 or unix time in TimestampUnit),
+	// like "/blockchain/head/timestamp". If empty the samples are exposed without a timestamp, so the
+	// scrape time is used.
+	TimestampPath string `json:"timestampPath"`
+
+	// TimestampUnit is the unit for the unix times in the value(valueType "timestamp") and in the
+	// TimestampPath, "ms" or "s", sa the KeyTimestampUnit constants. If empty the unix times are in
+	// milliseconds. The RFC3339 times does not need it.
+	TimestampUnit string `json:"timestampUnit"`
+
+	// Labels make the metric a vector, the value in Path should be an array or an object(map)
+	// and each item is exposed as a serie with the label values taken from the item.
+	// For info metrics the value in Path should be an object and the label values are taken from it.
+	Labels []Label `json:"labels"`
+
+	// Expression is how to compute a derived metric from other counters or gauges, like
+	// "unconfirmed / open_connections" or "sum(wallet*.open_connections)", sa the expression package.
+	Expression string `json:"expression"`
+
+	// Namespace and Subsystem overwrite the ones in the service to build the metric name.
+	Namespace string `json:"namespace"`
+	Subsystem string `json:"subsystem"`
+
 	Options          MetricOptions    `json:"options"`
-	HistogramOptions HistogramOptions `json:"histogram_options"`
+	HistogramOptions HistogramOptions `json:"histogramOptions"`
+	SummaryOptions   SummaryOptions   `json:"summaryOptions"`
+	InfoOptions      InfoOptions      `json:"infoOptions"`
+	CounterOptions   CounterOptions   `json:"counterOptions"`
+	MappingOptions   MappingOptions   `json:"mappingOptions"`
+}
+
+// IsInfo returns true if the metric is an info metric, sa KeyTypeInfo.
+func (metric Metric) IsInfo() bool {
+	return metric.Options.Type == KeyTypeInfo
+}
+
+// IsDerived returns true if the metric is computed from other metrics, sa KeyTypeDerived.
+func (metric Metric) IsDerived() bool {
+	return metric.Options.Type == KeyTypeDerived
 }
 
 func (metric Metric) isHistogram() bool {
@@ -30,10 +117,59 @@ func (metric Metric) isHistogram() bool {
 	return hasBuckets || metric.Options.Type == "Histogram"
 }
 
+// ValueTypeOrDefault returns the metric value type, KeyValueTypeNumber if it is not defined.
+func (metric Metric) ValueTypeOrDefault() string {
+	if len(metric.ValueType) == 0 {
+		return KeyValueTypeNumber
+	}
+	return metric.ValueType
+}
+
+// TimestampUnitOrDefault returns the unit for the unix times, KeyTimestampUnitMilliseconds if it is not defined.
+func (metric Metric) TimestampUnitOrDefault() string {
+	if len(metric.TimestampUnit) == 0 {
+		return KeyTimestampUnitMilliseconds
+	}
+	return metric.TimestampUnit
+}
+
+// LabelNames returns the names for the labels in this metric, including the state label(the metric name)
+// if the metric has states.
+func (metric Metric) LabelNames() (labelNames []string) {
+	for _, label := range metric.Labels {
+		labelNames = append(labelNames, label.Name)
+	}
+	if metric.IsStateSet() {
+		labelNames = append(labelNames, metric.Name)
+	}
+	return labelNames
+}
+
+// IsVec returns true if the metric has labels, so his path refer to a collection of values.
+func (metric Metric) IsVec() bool {
+	return len(metric.Labels) != 0
+}
+
+// IsStateSet returns true if the metric has states, exposed as a serie for each one with the state label.
+func (metric Metric) IsStateSet() bool {
+	return len(metric.MappingOptions.States) != 0
+}
+
+func (metric Metric) isSummary() bool {
+	return metric.SummaryOptions.inferType() == KeyTypeSummary || metric.Options.Type == KeyTypeSummary
+}
+
 func (metric Metric) validate() (errs []error) {
 	if len(metric.Name) == 0 {
 		errs = append(errs, errors.New("name is required in metric"))
 	}
+	if metric.IsDerived() {
+		errs = append(errs, metric.validateDerived()...)
+		return errs
+	}
+	if len(metric.Expression) != 0 {
+		errs = append(errs, errors.New("expression only apply for metrics of type derived"))
+	}
 	if len(metric.URL) == 0 {
 		errs = append(errs, errors.New("url is required in metric"))
 	}
@@ -46,10 +182,165 @@ func (metric Metric) validate() (errs []error) {
 	if metric.HistogramOptions.inferType() == "Histogram" && metric.Options.Type != "Histogram" {
 		errs = append(errs, errors.New("the buckets, only apply for metrics of type histogram"))
 	}
+	if metric.SummaryOptions.inferType() == KeyTypeSummary && metric.Options.Type != KeyTypeSummary {
+		errs = append(errs, errors.New("the objectives, max age and age buckets, only apply for metrics of type summary"))
+	}
+	if len(metric.ItemPath) != 0 && !metric.IsVec() {
+		errs = append(errs, errors.New("itemPath only apply for metrics with labels"))
+	}
+	if metric.CounterOptions.inferType() == KeyTypeCounter && metric.Options.Type != KeyTypeCounter {
+		errs = append(errs, errors.New("the accumulate option, only apply for metrics of type counter"))
+	}
+	if metric.InfoOptions.inferType() == KeyTypeInfo && !metric.IsInfo() {
+		errs = append(errs, errors.New("the max value length, only apply for metrics of type info"))
+	}
+	if metric.IsInfo() {
+		errs = append(errs, metric.validateInfo()...)
+	}
+	if len(metric.TimestampPath) != 0 {
+		if metric.Options.Type != KeyTypeCounter && metric.Options.Type != KeyTypeGauge && !metric.IsInfo() {
+			errs = append(errs, errors.New("timestampPath only apply for counters, gauges and info metrics"))
+		}
+		if !strings.HasPrefix(metric.TimestampPath, "/") {
+			errs = append(errs, fmt.Errorf("timestampPath %s should start with /", metric.TimestampPath))
+		}
+	}
+	if metric.MappingOptions.isDefined() {
+		errs = append(errs, metric.validateMapping()...)
+	}
+	switch metric.ValueTypeOrDefault() {
+	case KeyValueTypeNumber, KeyValueTypeBool, KeyValueTypeNumericString, KeyValueTypeDuration, KeyValueTypeTimestamp:
+	default:
+		errs = append(errs, fmt.Errorf(
+			"valueType should be one of '%s', '%s', '%s', '%s' or '%s'",
+			KeyValueTypeNumber, KeyValueTypeBool, KeyValueTypeNumericString, KeyValueTypeDuration, KeyValueTypeTimestamp))
+	}
+	if len(metric.TimestampUnit) != 0 {
+		if metric.ValueType != KeyValueTypeTimestamp && len(metric.TimestampPath) == 0 {
+			errs = append(errs, errors.New("timestampUnit only apply for metrics with a timestampPath or a timestamp valueType"))
+		}
+		if metric.TimestampUnit != KeyTimestampUnitMilliseconds && metric.TimestampUnit != KeyTimestampUnitSeconds {
+			errs = append(errs, fmt.Errorf(
+				"timestampUnit should be one of '%s' or '%s'", KeyTimestampUnitMilliseconds, KeyTimestampUnitSeconds))
+		}
+	}
+	errs = append(errs, metric.validateLabels()...)
 	errs = append(errs, metric.Options.validate()...)
 	if metric.isHistogram() {
 		errs = append(errs, metric.HistogramOptions.validate()...)
 	}
+	if metric.isSummary() {
+		errs = append(errs, metric.SummaryOptions.validate()...)
+	}
+	return errs
+}
+
+func (metric Metric) validateInfo() (errs []error) {
+	if !metric.IsVec() {
+		errs = append(errs, errors.New("info metrics require at least a label"))
+	}
+	if len(metric.ItemPath) != 0 {
+		errs = append(errs, errors.New("itemPath does not apply for info metrics"))
+	}
+	if len(metric.ValueType) != 0 {
+		errs = append(errs, errors.New("valueType does not apply for info metrics"))
+	}
+	for _, label := range metric.Labels {
+		if label.Path == KeyLabelPathItemKey {
+			errs = append(errs, fmt.Errorf("label path %s does not apply for info metrics", KeyLabelPathItemKey))
+		}
+	}
+	errs = append(errs, metric.InfoOptions.validate()...)
+	return errs
+}
+
+// validateDerived check the options for a derived metric, the metrics in the expression are checked
+// in the root config because they can be in other services.
+func (metric Metric) validateDerived() (errs []error) {
+	if len(metric.Expression) == 0 {
+		errs = append(errs, errors.New("expression is required in derived metrics"))
+	} else if _, err := expression.Parse(metric.Expression); err != nil {
+		errs = append(errs, err)
+	}
+	if len(metric.URL) != 0 || len(metric.HTTPMethod) != 0 || len(metric.Path) != 0 || len(metric.ItemPath) != 0 || len(metric.TimestampPath) != 0 {
+		errs = append(errs, errors.New("url, httpMethod, path, itemPath and timestampPath does not apply for derived metrics"))
+	}
+	if len(metric.ValueType) != 0 || metric.MappingOptions.isDefined() {
+		errs = append(errs, errors.New("valueType and mapping does not apply for derived metrics"))
+	}
+	if len(metric.Labels) != 0 {
+		errs = append(errs, errors.New("derived metrics can not have labels"))
+	}
+	if metric.HistogramOptions.inferType() == KeyTypeHistogram || metric.SummaryOptions.inferType() == KeyTypeSummary || metric.InfoOptions.inferType() == KeyTypeInfo || metric.CounterOptions.inferType() == KeyTypeCounter {
+		errs = append(errs, errors.New("histogram, summary, info and counter options does not apply for derived metrics"))
+	}
+	errs = append(errs, metric.Options.validate()...)
+	return errs
+}
+
+func (metric Metric) validateMapping() (errs []error) {
+	if len(metric.ValueType) != 0 {
+		errs = append(errs, errors.New("valueType does not apply for metrics with a mapping"))
+	}
+	if metric.IsInfo() {
+		errs = append(errs, errors.New("mapping does not apply for info metrics"))
+	}
+	if metric.IsStateSet() {
+		if metric.Options.Type != KeyTypeGauge {
+			errs = append(errs, errors.New("states only apply for metrics of type gauge"))
+		}
+		errs = append(errs, validateLabelName(metric.Name)...)
+	}
+	errs = append(errs, metric.MappingOptions.validate()...)
+	return errs
+}
+
+func (metric Metric) validateLabels() (errs []error) {
+	labelNames := make(map[string]bool)
+	for _, label := range metric.Labels {
+		errs = append(errs, label.validate()...)
+		if labelNames[label.Name] {
+			errs = append(errs, fmt.Errorf("label %s is defined more than once", label.Name))
+		}
+		labelNames[label.Name] = true
+	}
+	if metric.IsStateSet() && labelNames[metric.Name] {
+		errs = append(errs, fmt.Errorf("label %s is used for the states", metric.Name))
+	}
+	if metric.isHistogram() && labelNames["le"] {
+		errs = append(errs, errors.New("label name 'le' is reserved for histograms"))
+	}
+	if metric.isSummary() && labelNames["quantile"] {
+		errs = append(errs, errors.New("label name 'quantile' is reserved for summaries"))
+	}
+	return errs
+}
+
+// Label is a metric dimension, the value is taken from Path relative to each item in the metric path.
+type Label struct {
+	Name string `json:"name"`
+
+	// Path is where to find the label value inside each item, you can use KeyLabelPathItemKey to
+	// get the item key if the metric path refer to an object(map).
+	Path string `json:"path"`
+}
+
+// validateLabelName check if name can be used as a label name.
+func validateLabelName(name string) (errs []error) {
+	if !model.LabelName(name).IsValid() {
+		errs = append(errs, fmt.Errorf("label name %s is not a valid name", name))
+	}
+	if strings.HasPrefix(name, model.ReservedLabelPrefix) {
+		errs = append(errs, fmt.Errorf("label name %s is reserved for internal use", name))
+	}
+	return errs
+}
+
+func (label Label) validate() (errs []error) {
+	errs = append(errs, validateLabelName(label.Name)...)
+	if len(label.Path) == 0 {
+		errs = append(errs, errors.New("path is required in label"))
+	}
 	return errs
 }
 
@@ -57,12 +348,44 @@ func (metric Metric) validate() (errs []error) {
 type MetricOptions struct {
 	Type        string `json:"type"`
 	Description string `json:"description"`
+
+	// StalePolicy is what to expose when the metric can not be updated, "keep" the last success
+	// value(the default), "drop" the serie or expose a "nan" value(only for counters and gauges).
+	StalePolicy string `json:"stalePolicy"`
+
+	// StaleMaxAge is how long the last success value can be kept, after that the serie is dropped.
+	// Zero means forever.
+	StaleMaxAge time.Duration `json:"staleMaxAge"`
+}
+
+// StalePolicyOrDefault returns the configured stale policy or the default one if it is not defined.
+func (mo MetricOptions) StalePolicyOrDefault() string {
+	if len(mo.StalePolicy) == 0 {
+		return KeyStalePolicyKeep
+	}
+	return mo.StalePolicy
 }
 
 func (mo MetricOptions) validate() (errs []error) {
 	if len(mo.Type) == 0 {
 		errs = append(errs, errors.New("type is required in metric"))
 	}
+	switch mo.StalePolicyOrDefault() {
+	case KeyStalePolicyKeep:
+	case KeyStalePolicyNaN:
+		if mo.Type != KeyTypeCounter && mo.Type != KeyTypeGauge && mo.Type != KeyTypeDerived {
+			errs = append(errs, errors.New("the 'nan' stale policy only apply for counters, gauges and derived metrics"))
+		}
+	case KeyStalePolicyDrop:
+	default:
+		errs = append(errs, fmt.Errorf("stalePolicy should be one of '%s', '%s' or '%s'", KeyStalePolicyKeep, KeyStalePolicyDrop, KeyStalePolicyNaN))
+	}
+	if mo.StaleMaxAge < 0 {
+		errs = append(errs, errors.New("staleMaxAge should not be negative"))
+	}
+	if mo.StaleMaxAge != 0 && mo.StalePolicyOrDefault() != KeyStalePolicyKeep {
+		errs = append(errs, errors.New("staleMaxAge only apply for the 'keep' stale policy"))
+	}
 	return errs
 }
 
@@ -74,7 +397,7 @@ type HistogramOptions struct {
 	// - The first value is the low bound start bucket.
 	// - The second vale is the growing factor.
 	// - The three one is the buckets amount.
-	ExponentialBuckets []float64 `json:"exponential_buckets"`
+	ExponentialBuckets []float64 `json:"exponentialBuckets"`
 }
 
 func (ho HistogramOptions) validate() (errs []error) {
@@ -87,12 +410,185 @@ func (ho HistogramOptions) validate() (errs []error) {
 	if len(ho.ExponentialBuckets) != 0 && len(ho.ExponentialBuckets) != 3 {
 		errs = append(errs, errors.New("'exponentialBuckets' should have an exact length of 3(start, factor, amount)"))
 	}
+	if len(ho.ExponentialBuckets) == 3 {
+		if ho.ExponentialBuckets[0] <= 0 {
+			errs = append(errs, errors.New("'exponentialBuckets' start should be greater than 0"))
+		}
+		if ho.ExponentialBuckets[1] <= 1 {
+			errs = append(errs, errors.New("'exponentialBuckets' factor should be greater than 1"))
+		}
+		if ho.ExponentialBuckets[2] < 1 {
+			errs = append(errs, errors.New("'exponentialBuckets' amount should be at least 1"))
+		}
+	}
 	return errs
 }
 
+// BucketsBounds returns the upper bounds for the histogram buckets, computing them from
+// the 'exponentialBuckets' definition if any.
+func (ho HistogramOptions) BucketsBounds() []float64 {
+	if len(ho.ExponentialBuckets) == 3 {
+		return prometheus.ExponentialBuckets(ho.ExponentialBuckets[0], ho.ExponentialBuckets[1], int(ho.ExponentialBuckets[2]))
+	}
+	return ho.Buckets
+}
+
 func (ho HistogramOptions) inferType() (t string) {
 	if len(ho.Buckets) != 0 || len(ho.ExponentialBuckets) != 0 {
 		t = "Histogram"
 	}
 	return t
 }
+
+// Objective is a quantile to be tracked by a summary with his allowed absolute error.
+type Objective struct {
+	Quantile float64 `json:"quantile"`
+	Error    float64 `json:"error"`
+}
+
+// SummaryOptions allows you to define the summary is objectives and the observations window,
+// if some of them is not defined the prometheus default is used.
+type SummaryOptions struct {
+	Objectives []Objective `json:"objectives"`
+
+	// MaxAge is how long an observation stay relevant for the quantiles, for example "10m".
+	MaxAge time.Duration `json:"maxAge"`
+
+	// AgeBuckets is the number of buckets used to exclude observations older than MaxAge.
+	AgeBuckets uint32 `json:"ageBuckets"`
+}
+
+func (so SummaryOptions) validate() (errs []error) {
+	quantiles := make(map[float64]bool)
+	for _, objective := range so.Objectives {
+		if objective.Quantile <= 0 || objective.Quantile >= 1 {
+			errs = append(errs, fmt.Errorf("objective quantile %f should be betwen 0 and 1", objective.Quantile))
+		}
+		if objective.Error <= 0 || objective.Error >= 1 {
+			errs = append(errs, fmt.Errorf("objective error %f should be betwen 0 and 1", objective.Error))
+		}
+		if quantiles[objective.Quantile] {
+			errs = append(errs, fmt.Errorf("objective quantile %f is defined more than once", objective.Quantile))
+		}
+		quantiles[objective.Quantile] = true
+	}
+	if so.MaxAge < 0 {
+		errs = append(errs, errors.New("'maxAge' should not be negative"))
+	}
+	return errs
+}
+
+func (so SummaryOptions) inferType() (t string) {
+	if len(so.Objectives) != 0 || so.MaxAge != 0 || so.AgeBuckets != 0 {
+		t = KeyTypeSummary
+	}
+	return t
+}
+
+// InfoOptions allows you to limit the label values length for info metrics.
+type InfoOptions struct {
+	// MaxValueLength is the max length for the label values, the longer ones are truncated.
+	// If it is not defined(or 0) the values are not truncated.
+	MaxValueLength int `json:"maxValueLength"`
+}
+
+func (io InfoOptions) validate() (errs []error) {
+	if io.MaxValueLength < 0 {
+		errs = append(errs, errors.New("'maxValueLength' should not be negative"))
+	}
+	return errs
+}
+
+func (io InfoOptions) inferType() (t string) {
+	if io.MaxValueLength != 0 {
+		t = KeyTypeInfo
+	}
+	return t
+}
+
+// CounterOptions allows you to keep a counter monotonic when the upstream value is restarted.
+type CounterOptions struct {
+	// Accumulate the upstream increases instead of exposing the upstream value, a decrease is treated
+	// as a reset(the upstream value starts from zero again), so the exposed value never goes down.
+	Accumulate bool `json:"accumulate"`
+}
+
+func (co CounterOptions) inferType() (t string) {
+	if co.Accumulate {
+		t = KeyTypeCounter
+	}
+	return t
+}
+
+// MappingOptions allows you to get a number from a string value, only one of the mapping kinds can be used.
+type MappingOptions struct {
+	// Values is a table with the number for each string value.
+	Values []ValueMapping `json:"values"`
+
+	// States make the metric to be exposed like an OpenMetrics StateSet, with a serie for each state
+	// labeled with the metric name, the current state has a 1 value and the others a 0 value.
+	States []string `json:"states"`
+
+	// Regex has a capture group with the number inside the string value, for example `^\d+\.(\d+)\.\d+$`
+	// to get the minor version from "0.24.1".
+	Regex string `json:"regex"`
+}
+
+// ValueMapping is the number to be exposed for a string value.
+type ValueMapping struct {
+	Value  string  `json:"value"`
+	Number float64 `json:"number"`
+}
+
+func (mo MappingOptions) isDefined() bool {
+	return len(mo.Values) != 0 || len(mo.States) != 0 || len(mo.Regex) != 0
+}
+
+func (mo MappingOptions) validate() (errs []error) {
+	kinds := 0
+	if len(mo.Values) != 0 {
+		kinds++
+		values := make(map[string]bool)
+		for _, mapping := range mo.Values {
+			if values[mapping.Value] {
+				errs = append(errs, fmt.Errorf("value %s is mapped more than once", mapping.Value))
+			}
+			values[mapping.Value] = true
+		}
+	}
+	if len(mo.States) != 0 {
+		kinds++
+		states := make(map[string]bool)
+		for _, state := range mo.States {
+			if states[state] {
+				errs = append(errs, fmt.Errorf("state %s is defined more than once", state))
+			}
+			states[state] = true
+		}
+	}
+	if len(mo.Regex) != 0 {
+		kinds++
+		if regex, err := regexp.Compile(mo.Regex); err != nil {
+			errs = append(errs, fmt.Errorf("invalid regex %s: %s", mo.Regex, err.Error()))
+		} else if regex.NumSubexp() != 1 {
+			errs = append(errs, fmt.Errorf("regex %s should have a single capture group", mo.Regex))
+		}
+	}
+	if kinds > 1 {
+		errs = append(errs, errors.New("only one of values, states or regex can be used in a mapping"))
+	}
+	return errs
+}
+
+// ObjectivesMap returns the objectives as the quantile to absolute error map prometheus expect,
+// or nil if there are not objectives defined.
+func (so SummaryOptions) ObjectivesMap() (objectives map[float64]float64) {
+	if len(so.Objectives) == 0 {
+		return nil
+	}
+	objectives = make(map[float64]float64, len(so.Objectives))
+	for _, objective := range so.Objectives {
+		objectives[objective.Quantile] = objective.Error
+	}
+	return objectives
+}
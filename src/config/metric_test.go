@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -97,4 +98,409 @@ func (suite *metricConfSuit) TestNotEmptyPath() {
 	suite.Len(metricConf.validate(), 1)
 }
 
-// TODO(denisacostaq@gmail.com): test define buckets but declare type counter for example
+func (suite *metricConfSuit) TestBucketsInNotHistogramMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeCounter
+	metricConf.HistogramOptions.Buckets = []float64{1, 2, 3}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestHistogramWithoutBuckets() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeHistogram
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestHistogramWithInvalidExponentialBuckets() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeHistogram
+	metricConf.HistogramOptions.ExponentialBuckets = []float64{0, 1, 0}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 3)
+}
+
+func (suite *metricConfSuit) TestHistogramBucketsBoundsFromExponentialBuckets() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeHistogram
+	metricConf.HistogramOptions.ExponentialBuckets = []float64{1, 2, 4}
+
+	// NOTE(denisacostaq@gmail.com): When
+	bounds := metricConf.HistogramOptions.BucketsBounds()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 0)
+	suite.Equal([]float64{1, 2, 4, 8}, bounds)
+}
+
+func (suite *metricConfSuit) TestSummaryOptionsInNotSummaryMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeGauge
+	metricConf.SummaryOptions.AgeBuckets = 3
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestSummaryWithDefaultOptions() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeSummary
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 0)
+	suite.Nil(metricConf.SummaryOptions.ObjectivesMap())
+}
+
+func (suite *metricConfSuit) TestSummaryWithInvalidObjectives() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeSummary
+	metricConf.SummaryOptions.Objectives = []Objective{
+		Objective{Quantile: 0.5, Error: 0.05},
+		Objective{Quantile: 0.5, Error: 0.01},
+		Objective{Quantile: 1.5, Error: 0},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 3)
+}
+
+func (suite *metricConfSuit) TestValidLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Path = "/connections"
+	metricConf.ItemPath = "/height"
+	metricConf.Labels = []Label{Label{Name: "address", Path: "/address"}, Label{Name: "idx", Path: KeyLabelPathItemKey}}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 0)
+	suite.Equal([]string{"address", "idx"}, metricConf.LabelNames())
+}
+
+func (suite *metricConfSuit) TestInvalidLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Labels = []Label{
+		Label{Name: "1address", Path: "/address"},
+		Label{Name: "__address", Path: "/address"},
+		Label{Name: "height", Path: ""},
+		Label{Name: "height", Path: "/height"},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 4)
+}
+
+func (suite *metricConfSuit) TestItemPathWithoutLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.ItemPath = "/height"
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestStalePolicy() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.StalePolicy = KeyStalePolicyKeep
+	metricConf.Options.StaleMaxAge = time.Minute
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 0)
+}
+
+func (suite *metricConfSuit) TestInvalidStalePolicy() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.StalePolicy = "forget"
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestStaleMaxAgeWithoutKeepPolicy() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.StalePolicy = KeyStalePolicyDrop
+	metricConf.Options.StaleMaxAge = time.Minute
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestNaNStalePolicyInHistogram() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeHistogram
+	metricConf.HistogramOptions.Buckets = []float64{1}
+	metricConf.Options.StalePolicy = KeyStalePolicyNaN
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestValueType() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	suite.Equal(KeyValueTypeNumber, metricConf.ValueTypeOrDefault())
+	metricConf.ValueType = KeyValueTypeDuration
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 0)
+}
+
+func (suite *metricConfSuit) TestInvalidValueType() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.ValueType = "time"
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestInfoMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeInfo
+	metricConf.Path = "/version"
+	metricConf.Labels = []Label{Label{Name: "version", Path: "/version"}, Label{Name: "commit", Path: "/commit"}}
+	metricConf.InfoOptions.MaxValueLength = 8
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 0)
+	suite.True(metricConf.IsInfo())
+}
+
+func (suite *metricConfSuit) TestInvalidInfoMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeInfo
+	metricConf.Labels = []Label{Label{Name: "version", Path: KeyLabelPathItemKey}}
+	metricConf.ItemPath = "/version"
+	metricConf.InfoOptions.MaxValueLength = -1
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 3)
+}
+
+func (suite *metricConfSuit) TestInfoMetricWithoutLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeInfo
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestInfoOptionsInNotInfoMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.InfoOptions.MaxValueLength = 8
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestMapping() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeGauge
+
+	// NOTE(denisacostaq@gmail.com): When
+	metricConf.MappingOptions = MappingOptions{Values: []ValueMapping{ValueMapping{Value: "ok", Number: 1}}}
+	valuesErrs := metricConf.validate()
+	metricConf.MappingOptions = MappingOptions{States: []string{"syncing", "synced"}}
+	statesErrs := metricConf.validate()
+	metricConf.MappingOptions = MappingOptions{Regex: `^\d+\.(\d+)\.\d+$`}
+	regexErrs := metricConf.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(valuesErrs, 0)
+	suite.Len(statesErrs, 0)
+	suite.Len(regexErrs, 0)
+}
+
+func (suite *metricConfSuit) TestInvalidMapping() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.ValueType = KeyValueTypeNumericString
+	metricConf.MappingOptions = MappingOptions{
+		Values: []ValueMapping{ValueMapping{Value: "ok", Number: 1}, ValueMapping{Value: "ok", Number: 0}},
+		Regex:  `^(\d+)\.(\d+)$`,
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 4)
+}
+
+func (suite *metricConfSuit) TestStatesInNotGaugeMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.MappingOptions.States = []string{"syncing", "synced"}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+	suite.Equal([]string{metricConf.Name}, metricConf.LabelNames())
+}
+
+func (suite *metricConfSuit) TestInvalidRegexMapping() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.MappingOptions.Regex = `^(\d+`
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestDerivedMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	metricConf := Metric{
+		Name:       "unconfirmed_by_connection",
+		Expression: "unconfirmed / open_connections",
+		Options:    MetricOptions{Type: KeyTypeDerived, StalePolicy: KeyStalePolicyNaN},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 0)
+	suite.True(metricConf.IsDerived())
+}
+
+func (suite *metricConfSuit) TestInvalidDerivedMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Options.Type = KeyTypeDerived
+	metricConf.Expression = "unconfirmed /"
+	metricConf.Labels = []Label{Label{Name: "peer", Path: "/address"}}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 3)
+}
+
+func (suite *metricConfSuit) TestExpressionInNotDerivedMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.Expression = "unconfirmed / open_connections"
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(metricConf.validate(), 1)
+}
+
+func (suite *metricConfSuit) TestAccumulateInNotCounterMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.CounterOptions.Accumulate = true
+
+	// NOTE(denisacostaq@gmail.com): When
+	counterErrs := metricConf.validate()
+	metricConf.Options.Type = KeyTypeGauge
+	gaugeErrs := metricConf.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(counterErrs, 0)
+	suite.Len(gaugeErrs, 1)
+}
+
+func (suite *metricConfSuit) TestTimestampPath() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.TimestampPath = "/blockchain/head/timestamp"
+
+	// NOTE(denisacostaq@gmail.com): When
+	counterErrs := metricConf.validate()
+	metricConf.TimestampPath = "blockchain/head/timestamp"
+	relativeErrs := metricConf.validate()
+	metricConf.TimestampPath = "/blockchain/head/timestamp"
+	metricConf.Options.Type = KeyTypeHistogram
+	histogramErrs := metricConf.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(counterErrs, 0)
+	suite.Len(relativeErrs, 1)
+	suite.NotEmpty(histogramErrs)
+}
+
+func (suite *metricConfSuit) TestTimestampUnit() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var metricConf = suite.MetricConf
+	metricConf.TimestampPath = "/blockchain/head/timestamp"
+
+	// NOTE(denisacostaq@gmail.com): When
+	defaultUnit := metricConf.TimestampUnitOrDefault()
+	metricConf.TimestampUnit = KeyTimestampUnitSeconds
+	secondsErrs := metricConf.validate()
+	metricConf.TimestampUnit = "ns"
+	invalidErrs := metricConf.validate()
+	metricConf.TimestampUnit = KeyTimestampUnitSeconds
+	metricConf.TimestampPath = ""
+	withoutTimeErrs := metricConf.validate()
+	metricConf.ValueType = KeyValueTypeTimestamp
+	valueTypeErrs := metricConf.validate()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(KeyTimestampUnitMilliseconds, defaultUnit)
+	suite.Len(secondsErrs, 0)
+	suite.Len(invalidErrs, 1)
+	suite.Len(withoutTimeErrs, 1)
+	suite.Len(valueTypeErrs, 0)
+}
@@ -3,8 +3,22 @@ package config
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/simelo/rextporter/src/relabel"
+)
+
+const (
+	// defaultNamespace is used to build the metric names if there is not a namespace in the config.
+	defaultNamespace = "skycoin"
+	// KeyLabelService is the label with the service name if the service labels are enabled.
+	KeyLabelService = "service"
+	// KeyLabelLocation is the label with the service location if the service labels are enabled.
+	KeyLabelLocation = "location"
 )
 
 // Service is a concept to grab information about a running server, for example:
@@ -22,11 +36,108 @@ type Service struct {
 	TokenKeyFromEndpoint string   `json:"tokenKeyFromEndpoint"`
 	Location             Server   `json:"location"`
 	Metrics              []Metric `json:"metrics"`
+
+	// PollInterval make the service to be polled in background each interval(for example "15s"), so the
+	// scrapes are served from the latest values got instead of doing a request to the service in each one.
+	// If it is not defined the service is requested in each scrape.
+	PollInterval time.Duration `json:"pollInterval"`
+
+	// Namespace and Subsystem are used to build the metric names like namespace_subsystem_name, they
+	// overwrite the ones in the main config and can be overwritten in each metric. The default namespace
+	// is "skycoin" and the default subsystem is the service name.
+	Namespace string `json:"namespace"`
+	Subsystem string `json:"subsystem"`
+
+	// ServiceLabels make the service name and location to be exposed as the "service" and "location"
+	// labels instead of being part of the metric names, so the same metric from many services can be
+	// aggregated. If it is not defined the one in the main config is used.
+	ServiceLabels *bool `json:"serviceLabels"`
+
+	// ConstLabels are labels with a fixed value for all the metrics in the service, for example
+	// constLabels = { network = "mainnet" }. The label names are exposed in lower case.
+	ConstLabels map[string]string `json:"constLabels"`
+
+	// RelabelConfigs are Prometheus style relabel rules applied to the metrics in the service(including his
+	// health metrics) before the ones in the main config, sa relabel.Config.
+	RelabelConfigs []relabel.Config `json:"relabelConfigs"`
 }
 
-// MetricName returns a promehteus style name for the giving metric name.
-func (srv Service) MetricName(metricName string) string {
-	return prometheus.BuildFQName("skycoin", srv.Name, metricName)
+// HasServiceLabels returns true if the service name and location are exposed as labels.
+func (srv Service) HasServiceLabels() bool {
+	return srv.ServiceLabels != nil && *srv.ServiceLabels
+}
+
+// MetricNamespace returns the namespace for the giving metric, from the metric, the service or the default one.
+func (srv Service) MetricNamespace(metric Metric) string {
+	namespace := metric.Namespace
+	if len(namespace) == 0 {
+		namespace = srv.Namespace
+	}
+	if len(namespace) == 0 {
+		namespace = defaultNamespace
+	}
+	return namespace
+}
+
+// MetricName returns a promehteus style name for the giving metric, like namespace_subsystem_name.
+func (srv Service) MetricName(metric Metric) string {
+	namespace := srv.MetricNamespace(metric)
+	subsystem := metric.Subsystem
+	if len(subsystem) == 0 {
+		subsystem = srv.Subsystem
+	}
+	if len(subsystem) == 0 && !srv.HasServiceLabels() {
+		subsystem = srv.Name
+	}
+	return prometheus.BuildFQName(namespace, subsystem, metric.Name)
+}
+
+// ExposedMetricName returns the name the giving metric values are exposed with, the MetricName plus
+// the InfoMetricSuffix for the info metrics.
+func (srv Service) ExposedMetricName(metric Metric) string {
+	if metric.IsInfo() {
+		return srv.MetricName(metric) + InfoMetricSuffix
+	}
+	return srv.MetricName(metric)
+}
+
+// MetricConstLabels returns the labels with a fixed value for all the metrics in the service, including
+// the service name and location if the service labels are enabled.
+func (srv Service) MetricConstLabels() prometheus.Labels {
+	if len(srv.ConstLabels) == 0 && !srv.HasServiceLabels() {
+		return nil
+	}
+	labels := make(prometheus.Labels, len(srv.ConstLabels)+2)
+	for name, value := range srv.ConstLabels {
+		labels[name] = value
+	}
+	if srv.HasServiceLabels() {
+		labels[KeyLabelService] = srv.Name
+		labels[KeyLabelLocation] = srv.Location.Location
+	}
+	return labels
+}
+
+// metricKey identify a metric serie by the name and the const labels, two metrics with the same key
+// can not be exposed together.
+func (srv Service) metricKey(metric Metric) string {
+	constLabels := srv.MetricConstLabels()
+	pairs := make([]string, 0, len(constLabels))
+	for name, value := range constLabels {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return srv.MetricName(metric) + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// FilterMetricsByType will return all the metrics in this service who match whit the 't' parameter.
+func (srv Service) FilterMetricsByType(t string) (metrics []Metric) {
+	for _, metric := range srv.Metrics {
+		if metric.Options.Type == t {
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
 }
 
 // URIToGetMetric build the URI from where you will to get metric information
@@ -46,6 +157,9 @@ func (srv Service) validate() (errs []error) {
 	if len(srv.Scheme) == 0 {
 		errs = append(errs, errors.New("scheme is required in service"))
 	}
+	if srv.PollInterval < 0 {
+		errs = append(errs, errors.New("pollInterval should not be negative"))
+	}
 	if srv.Port < 1 || srv.Port > 65535 {
 		errs = append(errs, errors.New("port must be betwen 1 and 65535"))
 	}
@@ -56,7 +170,7 @@ func (srv Service) validate() (errs []error) {
 		errs = append(errs, errors.New("can not create a valid url to get token: "+srv.URIToGetToken()))
 	}
 	for _, metric := range srv.Metrics {
-		if !isValidURL(srv.URIToGetMetric(metric)) {
+		if !metric.IsDerived() && !isValidURL(srv.URIToGetMetric(metric)) {
 			errs = append(errs, errors.New("can not create a valid url to get metric: "+srv.URIToGetMetric(metric)))
 		}
 	}
@@ -69,8 +183,26 @@ func (srv Service) validate() (errs []error) {
 	if srv.AuthType == "CSRF" && len(srv.GenTokenEndpoint) == 0 {
 		errs = append(errs, errors.New("GenTokenEndpoint is required if you are using CSRF"))
 	}
+	for name := range srv.ConstLabels {
+		errs = append(errs, validateLabelName(name)...)
+		if srv.HasServiceLabels() && (name == KeyLabelService || name == KeyLabelLocation) {
+			errs = append(errs, fmt.Errorf("const label name %s is reserved if the service labels are enabled", name))
+		}
+	}
+	if _, err := relabel.CompileAll(srv.RelabelConfigs); err != nil {
+		errs = append(errs, fmt.Errorf("service %s: %s", srv.Name, err.Error()))
+	}
+	constLabels := srv.MetricConstLabels()
 	for _, metric := range srv.Metrics {
 		errs = append(errs, metric.validate()...)
+		if !model.IsValidMetricName(model.LabelValue(srv.MetricName(metric))) {
+			errs = append(errs, fmt.Errorf("%s is not a valid metric name", srv.MetricName(metric)))
+		}
+		for _, labelName := range metric.LabelNames() {
+			if _, ok := constLabels[labelName]; ok {
+				errs = append(errs, fmt.Errorf("label %s in metric %s is a service const label too", labelName, metric.Name))
+			}
+		}
 	}
 
 	errs = append(errs, srv.Location.validate()...)
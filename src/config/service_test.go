@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/suite"
 )
@@ -100,3 +101,99 @@ func (suite *serviceConfSuite) TestCsrfAuthButEmptyGenTokenEndpoint() {
 	// NOTE(denisacostaq@gmail.com): Assert
 	suite.Len(serviceConf.validate(), 1)
 }
+
+func (suite *serviceConfSuite) TestNegativePollInterval() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var serviceConf = suite.ServiceConf
+	serviceConf.PollInterval = -time.Second
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(serviceConf.validate(), 1)
+}
+
+func (suite *serviceConfSuite) TestDefaultMetricName() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var serviceConf = suite.ServiceConf
+	metric := Metric{Name: "seq"}
+
+	// NOTE(denisacostaq@gmail.com): When
+	name := serviceConf.MetricName(metric)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal("skycoin_MySupperServer_seq", name)
+	suite.Nil(serviceConf.MetricConstLabels())
+}
+
+func (suite *serviceConfSuite) TestMetricNameOverwrites() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var serviceConf = suite.ServiceConf
+	serviceConf.Namespace = "node"
+	serviceConf.Subsystem = "wallet"
+	metric := Metric{Name: "seq"}
+	metricWithSubsystem := Metric{Name: "seq", Subsystem: "blockchain"}
+
+	// NOTE(denisacostaq@gmail.com): When
+	name := serviceConf.MetricName(metric)
+	nameWithSubsystem := serviceConf.MetricName(metricWithSubsystem)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal("node_wallet_seq", name)
+	suite.Equal("node_blockchain_seq", nameWithSubsystem)
+}
+
+func (suite *serviceConfSuite) TestServiceLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var serviceConf = suite.ServiceConf
+	serviceLabels := true
+	serviceConf.ServiceLabels = &serviceLabels
+	serviceConf.ConstLabels = map[string]string{"network": "mainnet"}
+	metric := Metric{Name: "seq"}
+
+	// NOTE(denisacostaq@gmail.com): When
+	name := serviceConf.MetricName(metric)
+	constLabels := serviceConf.MetricConstLabels()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal("skycoin_seq", name)
+	suite.Equal("MySupperServer", constLabels[KeyLabelService])
+	suite.Equal("http://localhost:8080", constLabels[KeyLabelLocation])
+	suite.Equal("mainnet", constLabels["network"])
+	suite.Len(serviceConf.validate(), 0)
+}
+
+func (suite *serviceConfSuite) TestInvalidConstLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var serviceConf = suite.ServiceConf
+	serviceLabels := true
+	serviceConf.ServiceLabels = &serviceLabels
+	serviceConf.ConstLabels = map[string]string{"__network": "mainnet", "service": "node"}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(serviceConf.validate(), 2)
+}
+
+func (suite *serviceConfSuite) TestConstLabelAsMetricLabel() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	var serviceConf = suite.ServiceConf
+	serviceConf.ConstLabels = map[string]string{"address": "127.0.0.1"}
+	serviceConf.Metrics = []Metric{
+		Metric{
+			Name:       "height",
+			URL:        "/api/v1/network/connections",
+			HTTPMethod: "GET",
+			Path:       "/connections",
+			ItemPath:   "/height",
+			Labels:     []Label{Label{Name: "address", Path: "/address"}},
+			Options:    MetricOptions{Type: KeyTypeGauge},
+		},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(serviceConf.validate(), 1)
+}
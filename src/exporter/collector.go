@@ -1,48 +1,189 @@
 package exporter
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/simelo/rextporter/src/client"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
 	"github.com/simelo/rextporter/src/util"
 	log "github.com/sirupsen/logrus"
 )
 
 // SkycoinCollector has the metrics to be exposed
 type SkycoinCollector struct {
-	Counters []CounterMetric
-	Gauges   []GaugeMetric
+	Counters   []CounterMetric
+	Gauges     []GaugeMetric
+	Histograms []HistogramMetric
+	Summaries  []SummaryMetric
+	Deriveds   []DerivedMetric
+	poller     *poller
+	// services has the name of all the services, to expose the health metrics for each one.
+	services      []string
+	serviceHealth serviceHealth
+	// relabeler apply the relabel rules to the collected metrics, it is nil if there are not relabel rules.
+	relabeler *relabeler
 }
 
 func newSkycoinCollector() (collector *SkycoinCollector, err error) {
+	return newSkycoinCollectorFromConfig(config.Config())
+}
+
+// newSkycoinCollectorFromConfig creates a collector for all the metrics in the services from conf.
+func newSkycoinCollectorFromConfig(conf config.RootConfig) (collector *SkycoinCollector, err error) {
 	const generalScopeErr = "error creating collector"
 	collector = &SkycoinCollector{}
-	if collector.Counters, err = createCounters(); err != nil {
+	clients := make(dataClients)
+	if collector.Counters, err = createCounters(conf, clients); err != nil {
 		errCause := fmt.Sprintln("error creating counters: ", err.Error())
 		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
-	if collector.Gauges, err = createGauges(); err != nil {
+	if collector.Gauges, err = createGauges(conf, clients); err != nil {
 		errCause := fmt.Sprintln("error creating gauges: ", err.Error())
 		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
+	if collector.Histograms, err = createHistograms(conf, clients); err != nil {
+		errCause := fmt.Sprintln("error creating histograms: ", err.Error())
+		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	if collector.Summaries, err = createSummaries(conf, clients); err != nil {
+		errCause := fmt.Sprintln("error creating summaries: ", err.Error())
+		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	if collector.Deriveds, err = createDeriveds(conf, clients); err != nil {
+		errCause := fmt.Sprintln("error creating derived metrics: ", err.Error())
+		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	collector.poller = newPoller(collector.dataClients())
+	collector.services = serviceNames(conf)
+	collector.serviceHealth = newServiceHealth(conf.NamespaceOrDefault())
+	if collector.relabeler, err = newRelabeler(conf); err != nil {
+		errCause := fmt.Sprintln("error creating relabeler: ", err.Error())
+		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
 	return collector, err
 }
 
-// Describe writes all the descriptors to the prometheus desc channel.
+// Describe writes all the descriptors to the prometheus desc channel. The relabeled metrics can have any
+// name and labels, so nothing is described if there are relabel rules and the collector is unchecked.
 func (collector *SkycoinCollector) Describe(ch chan<- *prometheus.Desc) {
+	if collector.relabeler != nil {
+		return
+	}
 	for _, counter := range collector.Counters {
 		ch <- counter.MetricDesc
+		ch <- counter.StatusDesc
+		ch <- counter.LastSuccessDesc
 	}
 	for _, gauge := range collector.Gauges {
 		ch <- gauge.MetricDesc
+		ch <- gauge.StatusDesc
+		ch <- gauge.LastSuccessDesc
+	}
+	for _, histogram := range collector.Histograms {
+		histogram.Histogram.Describe(ch)
+		ch <- histogram.StatusDesc
+		ch <- histogram.LastSuccessDesc
+	}
+	for _, summary := range collector.Summaries {
+		summary.Summary.Describe(ch)
+		ch <- summary.StatusDesc
+		ch <- summary.LastSuccessDesc
+	}
+	for _, derived := range collector.Deriveds {
+		ch <- derived.MetricDesc
+		ch <- derived.StatusDesc
+		ch <- derived.LastSuccessDesc
+	}
+	collector.serviceHealth.describe(ch)
+}
+
+// resultValue returns the value for metricClient from the data got in this scrape and when that data was got,
+// or an error if the request fail, it was not able to finish on time or the service was not polled yet.
+func resultValue(results map[*client.DataClient]fetchResult, metricClient *client.MetricClient) (val interface{}, fetchedAt time.Time, err error) {
+	result, ok := results[metricClient.DataClient()]
+	if !ok {
+		return nil, fetchedAt, errors.New("there is not data, the scrape deadline was reached or the service was not polled yet")
+	}
+	if result.err != nil {
+		return nil, fetchedAt, result.err
+	}
+	val, err = metricClient.ValueFromData(result.data)
+	return val, result.fetchedAt, err
+}
+
+// collectLastSuccess writes the last success update time for a metric, if it was success updated at least once.
+func collectLastSuccess(ch chan<- prometheus.Metric, desc *prometheus.Desc, state *metricState) {
+	if lastSuccess, ok := state.lastSuccessTime(); ok {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(lastSuccess.UnixNano())/1e9)
+	}
+}
+
+// checkLabelValues returns an error if more than one item in labeledValues has the same label values, they
+// would be exposed as the same serie.
+func checkLabelValues(labeledValues []client.LabeledValue) error {
+	seen := make(map[string]bool, len(labeledValues))
+	for _, item := range labeledValues {
+		key := labelsKey(item.LabelValues)
+		if seen[key] {
+			return fmt.Errorf("label values %v are repeated in more than one item", item.LabelValues)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// samplesFromValue returns the samples to be exposed, val can be a single number or a labeled
+// collection of numbers with different label values.
+func samplesFromValue(val interface{}) (samples []sample, err error) {
+	switch typedVal := val.(type) {
+	case float64:
+		samples = []sample{sample{val: typedVal}}
+	case []client.LabeledValue:
+		if err = checkLabelValues(typedVal); err != nil {
+			return nil, err
+		}
+		samples = make([]sample, len(typedVal))
+		for idxItem, item := range typedVal {
+			var ok bool
+			if samples[idxItem].val, ok = item.Val.(float64); !ok {
+				return nil, fmt.Errorf("unable to get item %v(%v) as float64", item.LabelValues, item.Val)
+			}
+			samples[idxItem].labelValues = item.LabelValues
+		}
+	default:
+		return nil, fmt.Errorf("unable to get value(%v) as float64", val)
+	}
+	return samples, nil
+}
+
+// timestampSamples set the upstream time in samples if the metric has one, sa client.MetricClient.HasTimestamp.
+func timestampSamples(results map[*client.DataClient]fetchResult, metricClient *client.MetricClient, samples []sample) error {
+	if !metricClient.HasTimestamp() {
+		return nil
+	}
+	timestamp, err := metricClient.TimestampFromData(results[metricClient.DataClient()].data)
+	if err != nil {
+		return err
 	}
+	for idxSample := range samples {
+		samples[idxSample].timestamp = timestamp
+	}
+	return nil
 }
 
-func (collector *SkycoinCollector) collectCounters(ch chan<- prometheus.Metric) {
+func (collector *SkycoinCollector) collectCounters(ch chan<- prometheus.Metric, results map[*client.DataClient]fetchResult, failedMetrics map[string]int) {
 	onCollectFail := func(counter CounterMetric, fch chan<- prometheus.Metric) {
-		fch <- prometheus.MustNewConstMetric(counter.StatusDesc, prometheus.GaugeValue, 1)
-		fch <- prometheus.MustNewConstMetric(counter.MetricDesc, prometheus.CounterValue, counter.lastSuccessValue)
+		failedMetrics[counter.Client.DataClient().Service().Name]++
+		fch <- prometheus.MustNewConstMetric(counter.StatusDesc, prometheus.GaugeValue, 0)
+		for _, s := range counter.state.staleSamples(time.Now(), counter.isVec) {
+			fch <- constMetric(counter.MetricDesc, prometheus.CounterValue, s)
+		}
+		collectLastSuccess(fch, counter.LastSuccessDesc, counter.state)
 	}
 	recoverNegativeCounter := func(counter CounterMetric, fch chan<- prometheus.Metric) {
 		if r := recover(); r != nil {
@@ -57,57 +198,348 @@ func (collector *SkycoinCollector) collectCounters(ch chan<- prometheus.Metric)
 			onCollectFail(counter, fch)
 		}
 	}
-	onCollectSuccess := func(counter *CounterMetric, fch chan<- prometheus.Metric, val float64) {
-		defer recoverNegativeCounter(*counter, fch)
-		fch <- prometheus.MustNewConstMetric(counter.StatusDesc, prometheus.GaugeValue, 0)
-		fch <- prometheus.MustNewConstMetric(counter.MetricDesc, prometheus.CounterValue, val)
-		counter.lastSuccessValue = val
+	onCollectSuccess := func(counter CounterMetric, fch chan<- prometheus.Metric, samples []sample, fetchedAt time.Time) {
+		defer recoverNegativeCounter(counter, fch)
+		if counter.state.accumulate {
+			samples = counter.state.accumulated(samples, fetchedAt)
+		}
+		fch <- prometheus.MustNewConstMetric(counter.StatusDesc, prometheus.GaugeValue, 1)
+		for _, s := range samples {
+			fch <- constMetric(counter.MetricDesc, prometheus.CounterValue, s)
+		}
+		counter.state.succeeded(samples, fetchedAt)
+		collectLastSuccess(fch, counter.LastSuccessDesc, counter.state)
 	}
-	for idxCounter := range collector.Counters {
-		if val, err := collector.Counters[idxCounter].Client.GetMetric(); err != nil {
+	for _, counter := range collector.Counters {
+		if val, fetchedAt, err := resultValue(results, counter.Client); err != nil {
 			log.WithError(err).Errorln("can not get the data")
-			onCollectFail(collector.Counters[idxCounter], ch)
+			onCollectFail(counter, ch)
 		} else {
-			typedVal, ok := val.(float64)
-			if ok {
-				onCollectSuccess(&(collector.Counters[idxCounter]), ch, typedVal)
+			samples, err := samplesFromValue(val)
+			if err == nil {
+				err = timestampSamples(results, counter.Client, samples)
+			}
+			if err == nil {
+				onCollectSuccess(counter, ch, samples, fetchedAt)
 			} else {
-				log.WithField("val", val).Errorln("unable to get value as float64")
-				onCollectFail(collector.Counters[idxCounter], ch)
+				log.WithError(err).Errorln("unable to get the samples")
+				onCollectFail(counter, ch)
 			}
 		}
 	}
 }
 
-func (collector *SkycoinCollector) collectGauges(ch chan<- prometheus.Metric) {
+func (collector *SkycoinCollector) collectGauges(ch chan<- prometheus.Metric, results map[*client.DataClient]fetchResult, failedMetrics map[string]int) {
 	onCollectFail := func(gauge GaugeMetric, fch chan<- prometheus.Metric) {
+		failedMetrics[gauge.Client.DataClient().Service().Name]++
+		fch <- prometheus.MustNewConstMetric(gauge.StatusDesc, prometheus.GaugeValue, 0)
+		for _, s := range gauge.state.staleSamples(time.Now(), gauge.isVec) {
+			fch <- constMetric(gauge.MetricDesc, prometheus.GaugeValue, s)
+		}
+		collectLastSuccess(fch, gauge.LastSuccessDesc, gauge.state)
+	}
+	onCollectSuccess := func(gauge GaugeMetric, fch chan<- prometheus.Metric, samples []sample, fetchedAt time.Time) {
 		fch <- prometheus.MustNewConstMetric(gauge.StatusDesc, prometheus.GaugeValue, 1)
-		fch <- prometheus.MustNewConstMetric(gauge.MetricDesc, prometheus.GaugeValue, gauge.lastSuccessValue)
+		for _, s := range samples {
+			fch <- constMetric(gauge.MetricDesc, prometheus.GaugeValue, s)
+		}
+		gauge.state.succeeded(samples, fetchedAt)
+		collectLastSuccess(fch, gauge.LastSuccessDesc, gauge.state)
 	}
-	onCollectSuccess := func(gauge *GaugeMetric, fch chan<- prometheus.Metric, val float64) {
-		fch <- prometheus.MustNewConstMetric(gauge.StatusDesc, prometheus.GaugeValue, 0)
-		fch <- prometheus.MustNewConstMetric(gauge.MetricDesc, prometheus.GaugeValue, val)
-		gauge.lastSuccessValue = val
+	for _, gauge := range collector.Gauges {
+		if val, fetchedAt, err := resultValue(results, gauge.Client); err != nil {
+			log.WithError(err).Errorln("can not get the data")
+			onCollectFail(gauge, ch)
+		} else {
+			samples, err := samplesFromValue(val)
+			if err == nil {
+				err = timestampSamples(results, gauge.Client, samples)
+			}
+			if err == nil {
+				onCollectSuccess(gauge, ch, samples, fetchedAt)
+			} else {
+				log.WithError(err).Errorln("unable to get the samples")
+				onCollectFail(gauge, ch)
+			}
+		}
+	}
+}
+
+// observation is a set of values to be observed with the label values(if any) of the serie.
+type observation struct {
+	labelValues []string
+	values      []float64
+}
+
+// observableValues returns the values to be observed, val can be a single number, an array of numbers or a
+// labeled collection of any of them with different label values.
+func observableValues(val interface{}) (observations []observation, err error) {
+	numbers := func(val interface{}) (values []float64, err error) {
+		switch typedVal := val.(type) {
+		case float64:
+			values = []float64{typedVal}
+		case []interface{}:
+			values = make([]float64, len(typedVal))
+			for idxItem, item := range typedVal {
+				var ok bool
+				if values[idxItem], ok = item.(float64); !ok {
+					return nil, fmt.Errorf("unable to get item %d(%v) as float64", idxItem, item)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unable to get value(%v) as float64 or array of float64", val)
+		}
+		return values, nil
+	}
+	if labeledValues, ok := val.([]client.LabeledValue); ok {
+		if err = checkLabelValues(labeledValues); err != nil {
+			return nil, err
+		}
+		observations = make([]observation, len(labeledValues))
+		for idxItem, item := range labeledValues {
+			observations[idxItem].labelValues = item.LabelValues
+			if observations[idxItem].values, err = numbers(item.Val); err != nil {
+				return nil, err
+			}
+		}
+		return observations, nil
+	}
+	var values []float64
+	if values, err = numbers(val); err != nil {
+		return nil, err
+	}
+	return []observation{observation{values: values}}, nil
+}
+
+func (collector *SkycoinCollector) collectHistograms(ch chan<- prometheus.Metric, results map[*client.DataClient]fetchResult, failedMetrics map[string]int) {
+	onCollectFail := func(histogram HistogramMetric, fch chan<- prometheus.Metric) {
+		failedMetrics[histogram.Client.DataClient().Service().Name]++
+		fch <- prometheus.MustNewConstMetric(histogram.StatusDesc, prometheus.GaugeValue, 0)
+		if histogram.state.keepStale(time.Now()) {
+			histogram.Histogram.Collect(fch)
+		}
+		collectLastSuccess(fch, histogram.LastSuccessDesc, histogram.state)
 	}
-	for idxGauge := range collector.Gauges {
-		if val, err := collector.Gauges[idxGauge].Client.GetMetric(); err != nil {
+	onCollectSuccess := func(histogram HistogramMetric, fch chan<- prometheus.Metric, observations []observation, fetchedAt time.Time) {
+		for _, o := range observations {
+			if !histogram.state.observeOnce(o.labelValues, fetchedAt) {
+				continue
+			}
+			observer := histogram.Histogram.WithLabelValues(o.labelValues...)
+			for _, val := range o.values {
+				observer.Observe(val)
+			}
+		}
+		for _, labelValues := range histogram.state.dropUnobserved(observations, fetchedAt) {
+			histogram.Histogram.DeleteLabelValues(labelValues...)
+		}
+		histogram.state.succeeded(nil, fetchedAt)
+		fch <- prometheus.MustNewConstMetric(histogram.StatusDesc, prometheus.GaugeValue, 1)
+		histogram.Histogram.Collect(fch)
+		collectLastSuccess(fch, histogram.LastSuccessDesc, histogram.state)
+	}
+	for _, histogram := range collector.Histograms {
+		if val, fetchedAt, err := resultValue(results, histogram.Client); err != nil {
 			log.WithError(err).Errorln("can not get the data")
-			onCollectFail(collector.Gauges[idxGauge], ch)
+			onCollectFail(histogram, ch)
 		} else {
-			typedVal, ok := val.(float64)
-			if ok {
-				onCollectSuccess(&(collector.Gauges[idxGauge]), ch, typedVal)
+			observations, err := observableValues(val)
+			if err == nil {
+				onCollectSuccess(histogram, ch, observations, fetchedAt)
 			} else {
-				log.WithField("val", val).Errorln("unable to get value as float64")
-				onCollectFail(collector.Gauges[idxGauge], ch)
+				log.WithError(err).Errorln("unable to get values to observe")
+				onCollectFail(histogram, ch)
+			}
+		}
+	}
+}
+
+func (collector *SkycoinCollector) collectSummaries(ch chan<- prometheus.Metric, results map[*client.DataClient]fetchResult, failedMetrics map[string]int) {
+	onCollectFail := func(summary SummaryMetric, fch chan<- prometheus.Metric) {
+		failedMetrics[summary.Client.DataClient().Service().Name]++
+		fch <- prometheus.MustNewConstMetric(summary.StatusDesc, prometheus.GaugeValue, 0)
+		if summary.state.keepStale(time.Now()) {
+			summary.Summary.Collect(fch)
+		}
+		collectLastSuccess(fch, summary.LastSuccessDesc, summary.state)
+	}
+	onCollectSuccess := func(summary SummaryMetric, fch chan<- prometheus.Metric, observations []observation, fetchedAt time.Time) {
+		for _, o := range observations {
+			if !summary.state.observeOnce(o.labelValues, fetchedAt) {
+				continue
+			}
+			observer := summary.Summary.WithLabelValues(o.labelValues...)
+			for _, val := range o.values {
+				observer.Observe(val)
 			}
 		}
+		for _, labelValues := range summary.state.dropUnobserved(observations, fetchedAt) {
+			summary.Summary.DeleteLabelValues(labelValues...)
+		}
+		summary.state.succeeded(nil, fetchedAt)
+		fch <- prometheus.MustNewConstMetric(summary.StatusDesc, prometheus.GaugeValue, 1)
+		summary.Summary.Collect(fch)
+		collectLastSuccess(fch, summary.LastSuccessDesc, summary.state)
+	}
+	for _, summary := range collector.Summaries {
+		if val, fetchedAt, err := resultValue(results, summary.Client); err != nil {
+			log.WithError(err).Errorln("can not get the data")
+			onCollectFail(summary, ch)
+		} else {
+			observations, err := observableValues(val)
+			if err == nil {
+				onCollectSuccess(summary, ch, observations, fetchedAt)
+			} else {
+				log.WithError(err).Errorln("unable to get values to observe")
+				onCollectFail(summary, ch)
+			}
+		}
+	}
+}
+
+// forService returns a view of the collector with just the metrics in the service serviceName, the metrics
+// state and the data clients are shared with the collector.
+func (collector *SkycoinCollector) forService(serviceName string) *SkycoinCollector {
+	view := &SkycoinCollector{
+		poller:        collector.poller,
+		services:      []string{serviceName},
+		serviceHealth: collector.serviceHealth,
+		relabeler:     collector.relabeler,
+	}
+	inService := func(metricClient *client.MetricClient) bool {
+		return metricClient.DataClient().Service().Name == serviceName
+	}
+	for _, counter := range collector.Counters {
+		if inService(counter.Client) {
+			view.Counters = append(view.Counters, counter)
+		}
+	}
+	for _, gauge := range collector.Gauges {
+		if inService(gauge.Client) {
+			view.Gauges = append(view.Gauges, gauge)
+		}
+	}
+	for _, histogram := range collector.Histograms {
+		if inService(histogram.Client) {
+			view.Histograms = append(view.Histograms, histogram)
+		}
 	}
+	for _, summary := range collector.Summaries {
+		if inService(summary.Client) {
+			view.Summaries = append(view.Summaries, summary)
+		}
+	}
+	for _, derived := range collector.Deriveds {
+		if derived.service == serviceName {
+			view.Deriveds = append(view.Deriveds, derived)
+		}
+	}
+	return view
+}
+
+// dataClients returns the data clients shared by all the metrics in the collector, without duplicates.
+func (collector *SkycoinCollector) dataClients() (clients []*client.DataClient) {
+	var metricClients []*client.MetricClient
+	for _, counter := range collector.Counters {
+		metricClients = append(metricClients, counter.Client)
+	}
+	for _, gauge := range collector.Gauges {
+		metricClients = append(metricClients, gauge.Client)
+	}
+	for _, histogram := range collector.Histograms {
+		metricClients = append(metricClients, histogram.Client)
+	}
+	for _, summary := range collector.Summaries {
+		metricClients = append(metricClients, summary.Client)
+	}
+	for _, derived := range collector.Deriveds {
+		for _, sources := range derived.sources {
+			metricClients = append(metricClients, sources...)
+		}
+	}
+	added := make(map[*client.DataClient]bool)
+	for _, metricClient := range metricClients {
+		if !added[metricClient.DataClient()] {
+			added[metricClient.DataClient()] = true
+			clients = append(clients, metricClient.DataClient())
+		}
+	}
+	return clients
+}
+
+// states returns the state for all the metrics in the collector.
+func (collector *SkycoinCollector) states() (states []*metricState) {
+	for _, counter := range collector.Counters {
+		states = append(states, counter.state)
+	}
+	for _, gauge := range collector.Gauges {
+		states = append(states, gauge.state)
+	}
+	for _, histogram := range collector.Histograms {
+		states = append(states, histogram.state)
+	}
+	for _, summary := range collector.Summaries {
+		states = append(states, summary.state)
+	}
+	for _, derived := range collector.Deriveds {
+		states = append(states, derived.state)
+	}
+	return states
+}
+
+// fetch returns the data for all the endpoints, from the latest poll for the services polled in background
+// and from a new request for the others.
+func (collector *SkycoinCollector) fetch(ctx context.Context) (results map[*client.DataClient]fetchResult) {
+	var onDemandClients []*client.DataClient
+	polledResults := make(map[*client.DataClient]fetchResult)
+	for _, dataClient := range collector.dataClients() {
+		if !collector.poller.polls(dataClient) {
+			onDemandClients = append(onDemandClients, dataClient)
+		} else if result, ok := collector.poller.result(dataClient); ok {
+			polledResults[dataClient] = result
+		}
+	}
+	results = fetchAll(ctx, onDemandClients)
+	for dataClient, result := range polledResults {
+		results[dataClient] = result
+	}
+	return results
+}
+
+// collectWithTimeout get the data for all the endpoints concurrently, the metrics in the endpoints who are not
+// able to finish before timeout are reported as failed through the status("_up") metric. All the metrics in the
+// same endpoint are evaluated against the same document, so they are consistent between them.
+// If there are relabel rules they are applied to all the collected metrics.
+func (collector *SkycoinCollector) collectWithTimeout(ch chan<- prometheus.Metric, timeout time.Duration) {
+	if collector.relabeler != nil {
+		collector.relabeler.collect(ch, func(ch chan<- prometheus.Metric) {
+			collector.collectAll(ch, timeout)
+		})
+		return
+	}
+	collector.collectAll(ch, timeout)
+}
+
+// collectAll writes the metrics for all the endpoints without relabeling them, sa collectWithTimeout.
+func (collector *SkycoinCollector) collectAll(ch chan<- prometheus.Metric, timeout time.Duration) {
+	start := time.Now()
+	defer func() {
+		selfmetrics.ObserveCollection(time.Since(start))
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	results := collector.fetch(ctx)
+	failedMetrics := make(map[string]int)
+	collector.collectCounters(ch, results, failedMetrics)
+	collector.collectGauges(ch, results, failedMetrics)
+	collector.collectHistograms(ch, results, failedMetrics)
+	collector.collectSummaries(ch, results, failedMetrics)
+	// NOTE(denisacostaq@gmail.com): the derived metrics are evaluated after the source metrics
+	collector.collectDeriveds(ch, results, failedMetrics)
+	collector.collectServices(ch, results, failedMetrics)
 }
 
 //Collect update all the descriptors is values
-// TODO(denisacostaq@gmail.com): Make a research about race conditions here, "lastSuccessValue"
 func (collector *SkycoinCollector) Collect(ch chan<- prometheus.Metric) {
-	collector.collectCounters(ch)
-	collector.collectGauges(ch)
+	collector.collectWithTimeout(ch, defaultScrapeTimeout)
 }
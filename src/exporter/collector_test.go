@@ -0,0 +1,725 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+var healthResponse = `
+{
+    "blockchain": {
+        "head": {
+            "seq": 58894,
+            "fee": 485194
+        },
+        "unspents": 38171,
+        "unconfirmed": 1,
+        "time": "2018-09-22T02:00:04Z"
+    },
+    "version": {
+        "version": "0.24.1",
+        "commit": "8798b5ee43c7ce43b9b75d57a1a6cd2c1295cd1e",
+        "branch": "develop"
+    },
+    "open_connections": 8
+}
+`
+
+var connectionsResponse = `
+{
+    "connections": [
+        {"id": 1, "address": "139.162.161.41:20002", "height": 180, "outgoing": true},
+        {"id": 2, "address": "176.9.84.75:6000", "height": 185, "outgoing": true},
+        {"id": 3, "address": "185.120.34.60:6000", "height": 57, "outgoing": false}
+    ]
+}
+`
+
+// healthRequests count the requests to the health endpoint
+var healthRequests int32
+
+func httpHandler(w http.ResponseWriter, r *http.Request) {
+	var resp string
+	switch r.URL.Path {
+	case "/api/v1/health":
+		atomic.AddInt32(&healthRequests, 1)
+		resp = healthResponse
+	case "/api/v1/network/connections":
+		resp = connectionsResponse
+	case "/api/v1/slow/health":
+		select {
+		case <-r.Context().Done():
+			return
+		case <-time.After(time.Second):
+		}
+		resp = healthResponse
+	case "/api/v1/broken/health":
+		// NOTE(denisacostaq@gmail.com): a valid json document with an error status
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, err := w.Write([]byte(healthResponse)); err != nil {
+			log.WithError(err).Panicln("unable to write response")
+		}
+		return
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(resp)); err != nil {
+		log.WithError(err).Panicln("unable to write response")
+	}
+}
+
+type collectorSuit struct {
+	suite.Suite
+	testServer *httptest.Server
+	port       string
+}
+
+func (suite *collectorSuit) SetupSuite() {
+	suite.testServer = httptest.NewServer(http.HandlerFunc(httpHandler))
+	serverURL, err := url.Parse(suite.testServer.URL)
+	suite.Require().Nil(err)
+	suite.port = serverURL.Port()
+}
+
+func (suite *collectorSuit) TearDownSuite() {
+	suite.testServer.Close()
+}
+
+func TestCollectorSuit(t *testing.T) {
+	suite.Run(t, new(collectorSuit))
+}
+
+// serviceConf returns a service config pointing to the test server with the metrics section appended
+func (suite *collectorSuit) serviceConf(metricsConf string) string {
+	return suite.serviceConfWithOptions("", metricsConf)
+}
+
+// serviceConfWithOptions works like serviceConf but adding serviceOptions to the service section
+func (suite *collectorSuit) serviceConfWithOptions(serviceOptions, metricsConf string) string {
+	return fmt.Sprintf(`
+	[[services]]
+		name = "wallet"
+		scheme = "http"
+		port = %s
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+%s
+		[services.location]
+			location = "127.0.0.1"
+%s
+`, suite.port, serviceOptions, metricsConf)
+}
+
+// gather register a new collector created from the tomlConfig in a fresh registry and gather the metrics from it
+func gather(require *require.Assertions, tomlConfig string) map[string]*dto.MetricFamily {
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	return gatherFrom(require, collector)
+}
+
+// gatherFrom register collector in a fresh registry and gather the metrics from it
+func gatherFrom(require *require.Assertions, collector *SkycoinCollector) map[string]*dto.MetricFamily {
+	registry := prometheus.NewRegistry()
+	require.Nil(registry.Register(collector))
+	metricFamilies, err := registry.Gather()
+	require.Nil(err)
+	families := make(map[string]*dto.MetricFamily)
+	for _, mf := range metricFamilies {
+		families[mf.GetName()] = mf
+	}
+	return families
+}
+
+func (suite *collectorSuit) TestHistogramFromArray() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "connections_height"
+		url = "/api/v1/network/connections"
+		httpMethod = "GET"
+		path = "/connections[*]/height"
+
+		[services.metrics.options]
+			type = "Histogram"
+			description = "Peers height"
+
+		[services.metrics.histogramOptions]
+			buckets = [100, 200]
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_connections_height")
+	require.Contains(families, "skycoin_wallet_connections_height_up")
+	suite.Equal(float64(1), families["skycoin_wallet_connections_height_up"].GetMetric()[0].GetGauge().GetValue())
+	histogram := families["skycoin_wallet_connections_height"].GetMetric()[0].GetHistogram()
+	suite.Equal(uint64(3), histogram.GetSampleCount())
+	suite.Equal(float64(180+185+57), histogram.GetSampleSum())
+	require.Len(histogram.GetBucket(), 2)
+	suite.Equal(uint64(1), histogram.GetBucket()[0].GetCumulativeCount())
+	suite.Equal(uint64(3), histogram.GetBucket()[1].GetCumulativeCount())
+}
+
+func (suite *collectorSuit) TestHistogramFromNumber() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "open_connections"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/open_connections"
+
+		[services.metrics.options]
+			type = "Histogram"
+			description = "Open connections"
+
+		[services.metrics.histogramOptions]
+			exponentialBuckets = [1, 2, 4]
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_open_connections")
+	histogram := families["skycoin_wallet_open_connections"].GetMetric()[0].GetHistogram()
+	suite.Equal(uint64(1), histogram.GetSampleCount())
+	suite.Equal(float64(8), histogram.GetSampleSum())
+	require.Len(histogram.GetBucket(), 4)
+	suite.Equal(uint64(0), histogram.GetBucket()[2].GetCumulativeCount())
+	suite.Equal(uint64(1), histogram.GetBucket()[3].GetCumulativeCount())
+}
+
+func (suite *collectorSuit) TestSummary() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "fee"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/fee"
+
+		[services.metrics.options]
+			type = "Summary"
+			description = "Fee distribution"
+
+		[services.metrics.summaryOptions]
+			objectives = [{quantile = 0.5, error = 0.05}, {quantile = 0.9, error = 0.01}]
+			maxAge = "5m"
+			ageBuckets = 3
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_fee")
+	summary := families["skycoin_wallet_fee"].GetMetric()[0].GetSummary()
+	suite.Equal(uint64(1), summary.GetSampleCount())
+	suite.Equal(float64(485194), summary.GetSampleSum())
+	require.Len(summary.GetQuantile(), 2)
+	suite.Equal(0.5, summary.GetQuantile()[0].GetQuantile())
+	suite.Equal(float64(485194), summary.GetQuantile()[0].GetValue())
+}
+
+func (suite *collectorSuit) TestGaugeVecFromArray() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "open_connection_height"
+		url = "/api/v1/network/connections"
+		httpMethod = "GET"
+		path = "/connections"
+		itemPath = "/height"
+		labels = [{name = "address", path = "/address"}]
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Height reported by each peer"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_open_connection_height")
+	heights := make(map[string]float64)
+	for _, m := range families["skycoin_wallet_open_connection_height"].GetMetric() {
+		require.Len(m.GetLabel(), 1)
+		suite.Equal("address", m.GetLabel()[0].GetName())
+		heights[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	suite.Equal(map[string]float64{"139.162.161.41:20002": 180, "176.9.84.75:6000": 185, "185.120.34.60:6000": 57}, heights)
+}
+
+func (suite *collectorSuit) TestGaugeVecRepeatedLabelValues() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "connection_height"
+		url = "/api/v1/network/connections"
+		httpMethod = "GET"
+		path = "/connections"
+		itemPath = "/height"
+		labels = [{name = "outgoing", path = "/outgoing"}]
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Height reported by each peer"
+
+	[[services.metrics]]
+		name = "connection_id"
+		url = "/api/v1/network/connections"
+		httpMethod = "GET"
+		path = "/connections"
+		itemPath = "/id"
+		labels = [{name = "address", path = "/address"}]
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Id for each peer"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotContains(families, "skycoin_wallet_connection_height")
+	require.Contains(families, "skycoin_wallet_connection_height_up")
+	suite.Equal(float64(0), families["skycoin_wallet_connection_height_up"].GetMetric()[0].GetGauge().GetValue())
+	require.Contains(families, "skycoin_wallet_connection_id")
+	suite.Len(families["skycoin_wallet_connection_id"].GetMetric(), 3)
+}
+
+func (suite *collectorSuit) TestCounterVecFromObject() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "head"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head"
+		labels = [{name = "field", path = "#key"}]
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Blockchain head fields"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_head")
+	metrics := families["skycoin_wallet_head"].GetMetric()
+	require.Len(metrics, 2)
+	suite.Equal("fee", metrics[0].GetLabel()[0].GetValue())
+	suite.Equal(float64(485194), metrics[0].GetCounter().GetValue())
+	suite.Equal("seq", metrics[1].GetLabel()[0].GetValue())
+	suite.Equal(float64(58894), metrics[1].GetCounter().GetValue())
+}
+
+func (suite *collectorSuit) TestHistogramVecFromArray() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "peer_height"
+		url = "/api/v1/network/connections"
+		httpMethod = "GET"
+		path = "/connections"
+		itemPath = "/height"
+		labels = [{name = "id", path = "/id"}]
+
+		[services.metrics.options]
+			type = "Histogram"
+			description = "Height reported by each peer"
+
+		[services.metrics.histogramOptions]
+			buckets = [100, 200]
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_peer_height")
+	metrics := families["skycoin_wallet_peer_height"].GetMetric()
+	require.Len(metrics, 3)
+	suite.Equal("1", metrics[0].GetLabel()[0].GetValue())
+	suite.Equal(float64(180), metrics[0].GetHistogram().GetSampleSum())
+}
+
+func (suite *collectorSuit) TestMetricsInSameEndpointShareRequest() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Head sequence"
+
+	[[services.metrics]]
+		name = "unspents"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/unspents"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Unspents"
+
+	[[services.metrics]]
+		name = "fee"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/fee"
+
+		[services.metrics.options]
+			type = "Histogram"
+			description = "Fee"
+
+		[services.metrics.histogramOptions]
+			buckets = [1000]
+`)
+	require := require.New(suite.T())
+	atomic.StoreInt32(&healthRequests, 0)
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(int32(1), atomic.LoadInt32(&healthRequests))
+	suite.Equal(float64(58894), families["skycoin_wallet_seq"].GetMetric()[0].GetCounter().GetValue())
+	suite.Equal(float64(38171), families["skycoin_wallet_unspents"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(uint64(1), families["skycoin_wallet_fee"].GetMetric()[0].GetHistogram().GetSampleCount())
+}
+
+func (suite *collectorSuit) TestServiceLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	service := func(name string) string {
+		return fmt.Sprintf(`
+	[[services]]
+		name = "%s"
+		scheme = "http"
+		port = %s
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+		constLabels = { network = "testnet" }
+
+		[services.location]
+			location = "127.0.0.1"
+
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`, name, suite.port)
+	}
+	tomlConfig := `
+	namespace = "node"
+	serviceLabels = true
+` + service("node1") + service("node2")
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "node_seq")
+	require.Contains(families, "node_seq_up")
+	require.Contains(families, "node_service_up")
+	require.Len(families["node_seq"].GetMetric(), 2)
+	services := make(map[string]float64)
+	for _, m := range families["node_seq"].GetMetric() {
+		labels := make(map[string]string)
+		for _, label := range m.GetLabel() {
+			labels[label.GetName()] = label.GetValue()
+		}
+		suite.Equal("127.0.0.1", labels["location"])
+		suite.Equal("testnet", labels["network"])
+		services[labels["service"]] = m.GetGauge().GetValue()
+	}
+	suite.Equal(map[string]float64{"node1": 58894, "node2": 58894}, services)
+}
+
+func (suite *collectorSuit) TestInfoMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "version"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/version"
+		labels = [
+			{name = "version", path = "/version"},
+			{name = "commit", path = "/commit"},
+			{name = "branch", path = "/branch"}
+		]
+
+		[services.metrics.options]
+			type = "Info"
+			description = "Node version"
+
+		[services.metrics.infoOptions]
+			maxValueLength = 7
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_version_info")
+	require.Contains(families, "skycoin_wallet_version_up")
+	require.Len(families["skycoin_wallet_version_info"].GetMetric(), 1)
+	m := families["skycoin_wallet_version_info"].GetMetric()[0]
+	suite.Equal(float64(1), m.GetGauge().GetValue())
+	labels := make(map[string]string)
+	for _, label := range m.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	suite.Equal(map[string]string{"version": "0.24.1", "commit": "8798b5e", "branch": "develop"}, labels)
+}
+
+func (suite *collectorSuit) TestSampleTimestamp() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Last block sequence"
+
+	[[services.metrics]]
+		name = "unspents"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/unspents"
+		timestampPath = "/version/branch"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Unspent outputs"
+
+	[[services.metrics]]
+		name = "open_connections"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/open_connections"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Open connections"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_seq")
+	seq := families["skycoin_wallet_seq"].GetMetric()[0]
+	suite.Equal(float64(58894), seq.GetCounter().GetValue())
+	suite.Equal(int64(1537581604000), seq.GetTimestampMs())
+	suite.Nil(families["skycoin_wallet_seq_up"].GetMetric()[0].TimestampMs)
+	suite.NotContains(families, "skycoin_wallet_unspents")
+	suite.Equal(float64(0), families["skycoin_wallet_unspents_up"].GetMetric()[0].GetGauge().GetValue())
+	require.Contains(families, "skycoin_wallet_open_connections")
+	suite.Nil(families["skycoin_wallet_open_connections"].GetMetric()[0].TimestampMs)
+}
+
+// selfCounter returns the value in the self metric counter name for metric in service
+func selfCounter(require *require.Assertions, name, service, metric string) (count float64) {
+	metricFamilies, err := selfmetrics.Registry().Gather()
+	require.Nil(err)
+	for _, mf := range metricFamilies {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["service"] == service && labels["metric"] == metric {
+				count += m.GetCounter().GetValue()
+			}
+		}
+	}
+	return count
+}
+
+func (suite *collectorSuit) TestStateSet() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "branch"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/version/branch"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Branch from which the node was built"
+
+		[services.metrics.mappingOptions]
+			states = ["master", "develop"]
+
+	[[services.metrics]]
+		name = "commit"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/version/commit"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Commit from which the node was built"
+
+		[services.metrics.mappingOptions]
+			states = ["release"]
+`)
+	require := require.New(suite.T())
+	unknownBefore := selfCounter(require, "rextporter_unknown_values_total", "wallet", "commit")
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_branch")
+	states := make(map[string]float64)
+	for _, m := range families["skycoin_wallet_branch"].GetMetric() {
+		require.Len(m.GetLabel(), 1)
+		suite.Equal("branch", m.GetLabel()[0].GetName())
+		states[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	suite.Equal(map[string]float64{"master": 0, "develop": 1}, states)
+	require.Contains(families, "skycoin_wallet_commit")
+	require.Len(families["skycoin_wallet_commit"].GetMetric(), 1)
+	suite.Equal(float64(0), families["skycoin_wallet_commit"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(float64(1), families["skycoin_wallet_commit_up"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(unknownBefore+1, selfCounter(require, "rextporter_unknown_values_total", "wallet", "commit"))
+}
+
+func (suite *collectorSuit) TestDerivedMetric() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	service := func(name, derivedConf string) string {
+		return fmt.Sprintf(`
+	[[services]]
+		name = "%s"
+		scheme = "http"
+		port = %s
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "127.0.0.1"
+
+	[[services.metrics]]
+		name = "unconfirmed"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/unconfirmed"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Unconfirmed transactions"
+
+	[[services.metrics]]
+		name = "open_connections"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/open_connections"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Open connections"
+%s
+`, name, suite.port, derivedConf)
+	}
+	tomlConfig := service("wallet1", `
+	[[services.metrics]]
+		name = "unconfirmed_by_connection"
+		expression = "unconfirmed / open_connections"
+
+		[services.metrics.options]
+			type = "Derived"
+			description = "Unconfirmed transactions by connection"
+
+	[[services.metrics]]
+		name = "wallets_open_connections"
+		expression = "sum(wallet*.open_connections)"
+
+		[services.metrics.options]
+			type = "Derived"
+			description = "Open connections in all the wallets"
+
+	[[services.metrics]]
+		name = "invalid_division"
+		expression = "open_connections / (wallet2.unconfirmed - 1)"
+
+		[services.metrics.options]
+			type = "Derived"
+			description = "Always fail"
+`) + service("wallet2", "")
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet1_unconfirmed_by_connection")
+	suite.Equal(0.125, families["skycoin_wallet1_unconfirmed_by_connection"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(float64(1), families["skycoin_wallet1_unconfirmed_by_connection_up"].GetMetric()[0].GetGauge().GetValue())
+	require.Contains(families, "skycoin_wallet1_wallets_open_connections")
+	suite.Equal(float64(16), families["skycoin_wallet1_wallets_open_connections"].GetMetric()[0].GetGauge().GetValue())
+	suite.NotContains(families, "skycoin_wallet1_invalid_division")
+	suite.Equal(float64(0), families["skycoin_wallet1_invalid_division_up"].GetMetric()[0].GetGauge().GetValue())
+}
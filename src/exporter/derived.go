@@ -0,0 +1,124 @@
+package exporter
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/simelo/rextporter/src/client"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/expression"
+	"github.com/simelo/rextporter/src/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// DerivedMetric is a gauge with the value of an expression over other metrics, it is evaluated in each scrape
+// from the same data used for the source metrics.
+type DerivedMetric struct {
+	service         string
+	expression      *expression.Expression
+	sources         map[expression.Ref][]*client.MetricClient
+	MetricDesc      *prometheus.Desc
+	StatusDesc      *prometheus.Desc
+	LastSuccessDesc *prometheus.Desc
+	state           *metricState
+}
+
+func createDerived(metricConf config.Metric, srvConf config.Service, conf config.RootConfig, clients dataClients) (metric DerivedMetric, err error) {
+	generalScopeErr := "can not create metric " + metricConf.Name
+	var expr *expression.Expression
+	if expr, err = expression.Parse(metricConf.Expression); err != nil {
+		errCause := fmt.Sprintln("error parsing the expression: ", err.Error())
+		return metric, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	metric = DerivedMetric{
+		service:         srvConf.Name,
+		expression:      expr,
+		sources:         make(map[expression.Ref][]*client.MetricClient),
+		MetricDesc:      prometheus.NewDesc(srvConf.MetricName(metricConf), metricConf.Options.Description, nil, srvConf.MetricConstLabels()),
+		StatusDesc:      statusDesc(metricConf, srvConf),
+		LastSuccessDesc: lastSuccessDesc(metricConf, srvConf),
+		state:           newMetricState(metricConf, srvConf),
+	}
+	for _, ref := range expr.Refs() {
+		for _, source := range conf.DerivedSources(srvConf, ref) {
+			var metricClient *client.MetricClient
+			if metricClient, err = clients.metricClient(source.Metric, source.Service); err != nil {
+				errCause := fmt.Sprintln("error creating metric client: ", err.Error())
+				return metric, util.ErrorFromThisScope(errCause, generalScopeErr)
+			}
+			metric.sources[ref] = append(metric.sources[ref], metricClient)
+		}
+	}
+	return metric, nil
+}
+
+func createDeriveds(conf config.RootConfig, clients dataClients) ([]DerivedMetric, error) {
+	generalScopeErr := "can not create derived metrics"
+	var deriveds []DerivedMetric
+	for _, srvConf := range conf.Services {
+		for _, metric := range srvConf.FilterMetricsByType(config.KeyTypeDerived) {
+			derived, err := createDerived(metric, srvConf, conf, clients)
+			if err != nil {
+				errCause := fmt.Sprintln("error creating derived metric: ", err.Error())
+				return []DerivedMetric{}, util.ErrorFromThisScope(errCause, generalScopeErr)
+			}
+			deriveds = append(deriveds, derived)
+		}
+	}
+	return deriveds, nil
+}
+
+// value evaluates the expression with the source values from results, fetchedAt is when the oldest
+// source data was got.
+func (derived DerivedMetric) value(results map[*client.DataClient]fetchResult) (val float64, fetchedAt time.Time, err error) {
+	val, err = derived.expression.Eval(func(ref expression.Ref) (values []float64, err error) {
+		if len(derived.sources[ref]) == 0 {
+			// NOTE(denisacostaq@gmail.com): like a service pattern who does not match any service
+			return nil, fmt.Errorf("there are not sources for %s", ref)
+		}
+		for _, source := range derived.sources[ref] {
+			var sourceVal interface{}
+			var sourceFetchedAt time.Time
+			if sourceVal, sourceFetchedAt, err = resultValue(results, source); err != nil {
+				return nil, fmt.Errorf("can not get the value for %s: %s", ref, err.Error())
+			}
+			var samples []sample
+			if samples, err = samplesFromValue(sourceVal); err != nil {
+				return nil, fmt.Errorf("can not get the value for %s: %s", ref, err.Error())
+			}
+			for _, s := range samples {
+				values = append(values, s.val)
+			}
+			if fetchedAt.IsZero() || sourceFetchedAt.Before(fetchedAt) {
+				fetchedAt = sourceFetchedAt
+			}
+		}
+		return values, nil
+	})
+	if err == nil && fetchedAt.IsZero() {
+		// NOTE(denisacostaq@gmail.com): an expression without references, like "60 * 60"
+		fetchedAt = time.Now()
+	}
+	return val, fetchedAt, err
+}
+
+func (collector *SkycoinCollector) collectDeriveds(ch chan<- prometheus.Metric, results map[*client.DataClient]fetchResult, failedMetrics map[string]int) {
+	for _, derived := range collector.Deriveds {
+		val, fetchedAt, err := derived.value(results)
+		if err != nil {
+			log.WithError(err).WithField("expression", derived.expression.String()).Errorln("can not evaluate the expression")
+			failedMetrics[derived.service]++
+			ch <- prometheus.MustNewConstMetric(derived.StatusDesc, prometheus.GaugeValue, 0)
+			for _, s := range derived.state.staleSamples(time.Now(), false) {
+				ch <- prometheus.MustNewConstMetric(derived.MetricDesc, prometheus.GaugeValue, s.val)
+			}
+			collectLastSuccess(ch, derived.LastSuccessDesc, derived.state)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(derived.StatusDesc, prometheus.GaugeValue, 1)
+		ch <- prometheus.MustNewConstMetric(derived.MetricDesc, prometheus.GaugeValue, val)
+		derived.state.succeeded([]sample{sample{val: val}}, fetchedAt)
+		collectLastSuccess(ch, derived.LastSuccessDesc, derived.state)
+	}
+}
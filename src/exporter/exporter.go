@@ -1,26 +1,171 @@
 package exporter
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"path"
+	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
+	"github.com/simelo/rextporter/src/util"
 	log "github.com/sirupsen/logrus"
 )
 
+var (
+	hooksMutex sync.Mutex
+	// pendingHooks has the shutdown hooks who are not finished yet for each server created by ExportMetrics.
+	pendingHooks = make(map[*http.Server]*sync.WaitGroup)
+)
+
+// onShutdown register hook to be called when srv is shutdown, sa Shutdown.
+func onShutdown(srv *http.Server, hook func()) {
+	hooksMutex.Lock()
+	defer hooksMutex.Unlock()
+	hooks, ok := pendingHooks[srv]
+	if !ok {
+		hooks = new(sync.WaitGroup)
+		pendingHooks[srv] = hooks
+	}
+	hooks.Add(1)
+	srv.RegisterOnShutdown(func() {
+		defer hooks.Done()
+		hook()
+	})
+}
+
+// Shutdown gracefully stop srv(sa http.Server.Shutdown) and wait for his shutdown hooks to finish,
+// like saving the metrics state, until ctx is done.
+func Shutdown(ctx context.Context, srv *http.Server) (err error) {
+	err = srv.Shutdown(ctx)
+	hooksMutex.Lock()
+	hooks, ok := pendingHooks[srv]
+	delete(pendingHooks, srv)
+	hooksMutex.Unlock()
+	if !ok {
+		return err
+	}
+	finished := make(chan struct{})
+	go func() {
+		hooks.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// newStateStoreFromConfig returns a store for the state of the metrics in collector if the state should
+// be persisted, the saved state(if any) is restored.
+func newStateStoreFromConfig(conf config.RootConfig, collector *SkycoinCollector) (store *stateStore, err error) {
+	const generalScopeErr = "error creating the metrics state store"
+	if !conf.State.Persist {
+		return nil, nil
+	}
+	var path string
+	if path, err = conf.State.FilePath(); err != nil {
+		return nil, util.ErrorFromThisScope(err.Error(), generalScopeErr)
+	}
+	store = newStateStore(path, conf.State.IntervalOrDefault(), collector.states())
+	if err = store.load(); err != nil {
+		return nil, util.ErrorFromThisScope(err.Error(), generalScopeErr)
+	}
+	return store, nil
+}
+
+// newServeMux returns the handlers for the metrics in collector, each server get his own mux so many of them
+// can be started with the same endpoints, sa ExportMetrics.
+func newServeMux(collector *SkycoinCollector, handlerEndpoint, selfHandlerEndpoint string) (mux *http.ServeMux) {
+	mux = http.NewServeMux()
+	if len(selfHandlerEndpoint) == 0 {
+		mux.Handle(handlerEndpoint, newMetricsHandler(collector, prometheus.DefaultGatherer, selfmetrics.Registry()))
+	} else {
+		mux.Handle(handlerEndpoint, newMetricsHandler(collector, prometheus.DefaultGatherer))
+	}
+	mux.Handle(path.Join(handlerEndpoint, influxEndpoint), newInfluxHandler(collector))
+	mux.Handle(handlerEndpoint+snapshotEndpointSuffix, newSnapshotHandler(collector, config.Config()))
+	for _, serviceName := range collector.services {
+		if serviceName == influxEndpoint {
+			log.WithField("service", serviceName).Errorln("the service metrics can not be served under his own path because it is used by the InfluxDB metrics")
+			continue
+		}
+		mux.Handle(path.Join(handlerEndpoint, serviceName), newMetricsHandler(collector.forService(serviceName)))
+	}
+	mux.Handle(probeEndpoint, newProbeHandler())
+	if len(selfHandlerEndpoint) != 0 {
+		mux.Handle(selfHandlerEndpoint, promhttp.HandlerFor(selfmetrics.Registry(), promhttp.HandlerOpts{}))
+	}
+	return mux
+}
+
 // ExportMetrics will read the config from mainConfigFile if any or use a default one.
-func ExportMetrics(mainConfigFile, handlerEndpoint string, listenPort uint16) (srv *http.Server) {
+// The metrics state is restored and saved in background if it should be persisted, sa Shutdown.
+// The metrics are pushed to a Pushgateway in background if a push url is defined, sa config.PushOptions.
+// The metrics are sent to a remote write endpoint in background if it is defined, sa config.RemoteWriteOptions.
+// The metrics are sent to each Graphite or StatsD sink in background, sa config.SinkOptions.
+// The metrics are sent to an OpenTelemetry collector in background if it is defined, sa config.OTLPOptions.
+// The metrics for each service are served under handlerEndpoint/<service name> too.
+// The same metrics are served in the InfluxDB line protocol under handlerEndpoint/influx and as a json
+// snapshot under handlerEndpoint.json.
+// The rextporter own metrics are served under selfHandlerEndpoint, or with the services metrics
+// under handlerEndpoint if selfHandlerEndpoint is empty.
+// Each server has his own handlers(including the "/probe" one), so many of them can be started.
+func ExportMetrics(mainConfigFile, handlerEndpoint, selfHandlerEndpoint string, listenPort uint16) (srv *http.Server) {
 	config.NewConfigFromFileSystem(mainConfigFile)
-	if collector, err := newSkycoinCollector(); err != nil {
+	collector, err := newSkycoinCollector()
+	if err != nil {
 		log.WithError(err).Panicln("Can not create metrics")
-	} else {
-		prometheus.MustRegister(collector)
 	}
+	var store *stateStore
+	if store, err = newStateStoreFromConfig(config.Config(), collector); err != nil {
+		log.WithError(err).Panicln("Can not restore the metrics state")
+	}
+	var metricsPusher *pusher
+	if metricsPusher, err = newPusherFromConfig(config.Config(), collector); err != nil {
+		log.WithError(err).Panicln("Can not create the metrics pusher")
+	}
+	var writer *remoteWriter
+	if writer, err = newRemoteWriterFromConfig(config.Config(), collector); err != nil {
+		log.WithError(err).Panicln("Can not create the remote writer")
+	}
+	var sinkPushers []*sinkPusher
+	if sinkPushers, err = newSinkPushersFromConfig(config.Config(), collector); err != nil {
+		log.WithError(err).Panicln("Can not create the sinks")
+	}
+	var otlp *otlpExporter
+	if otlp, err = newOTLPExporterFromConfig(config.Config(), collector); err != nil {
+		log.WithError(err).Panicln("Can not create the otlp exporter")
+	}
+	collector.poller.start()
 	port := fmt.Sprintf(":%d", listenPort)
-	srv = &http.Server{Addr: port}
-	http.Handle(handlerEndpoint, promhttp.Handler())
+	srv = &http.Server{Addr: port, Handler: newServeMux(collector, handlerEndpoint, selfHandlerEndpoint)}
+	onShutdown(srv, collector.poller.stopPolling)
+	if store != nil {
+		store.start()
+		onShutdown(srv, store.stopSaving)
+	}
+	if metricsPusher != nil {
+		metricsPusher.start()
+		onShutdown(srv, metricsPusher.stopPushing)
+	}
+	if writer != nil {
+		writer.start()
+		onShutdown(srv, writer.stopWriting)
+	}
+	for _, sinkPusher := range sinkPushers {
+		sinkPusher.start()
+		onShutdown(srv, sinkPusher.stopSinking)
+	}
+	if otlp != nil {
+		otlp.start()
+		onShutdown(srv, otlp.stopExporting)
+	}
 	go func() {
 		log.Infoln(fmt.Sprintf("Starting server in port %d, path %s ...", listenPort, handlerEndpoint))
 		log.WithError(srv.ListenAndServe()).Errorln("unable to start the server")
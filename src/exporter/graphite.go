@@ -0,0 +1,46 @@
+package exporter
+
+import (
+	"bytes"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+// graphiteSink send the samples to Graphite with the plaintext protocol over TCP, a new connection is used
+// in each send so a Graphite restart does not need to be detected.
+type graphiteSink struct {
+	address string
+	timeout time.Duration
+}
+
+// writeGraphiteLines writes samples in the Graphite plaintext protocol, like "<path> <value> <unix seconds>".
+func writeGraphiteLines(buf *bytes.Buffer, samples []sinkSample) {
+	for _, sample := range samples {
+		if math.IsNaN(sample.value) || math.IsInf(sample.value, 0) {
+			continue
+		}
+		buf.WriteString(sample.path)
+		buf.WriteByte(' ')
+		buf.WriteString(formatFloat(sample.value))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(sample.timestamp.Unix(), 10))
+		buf.WriteByte('\n')
+	}
+}
+
+func (s graphiteSink) send(samples []sinkSample) (err error) {
+	buf := new(bytes.Buffer)
+	writeGraphiteLines(buf, samples)
+	var conn net.Conn
+	if conn, err = net.DialTimeout("tcp", s.address, s.timeout); err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	_, err = conn.Write(buf.Bytes())
+	return err
+}
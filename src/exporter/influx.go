@@ -0,0 +1,68 @@
+package exporter
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// influxEndpoint is added to the metrics handler endpoint to serve the metrics in the InfluxDB line protocol.
+	influxEndpoint = "influx"
+	// influxField is the field name for the serie value.
+	influxField = "value"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxTagEscaper         = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+)
+
+// writeInfluxLines writes series in the InfluxDB line protocol, like "<name>,<label>=<value> value=<value> <ns>".
+// The labels with an empty value and the not finite values are skipped because they can not be written.
+func writeInfluxLines(buf *bytes.Buffer, series []timeSeries) {
+	for _, serie := range series {
+		if math.IsNaN(serie.value) || math.IsInf(serie.value, 0) {
+			continue
+		}
+		buf.WriteString(influxMeasurementEscaper.Replace(serie.name))
+		for _, label := range serie.labels {
+			if len(label.GetValue()) == 0 {
+				continue
+			}
+			buf.WriteByte(',')
+			buf.WriteString(influxTagEscaper.Replace(label.GetName()))
+			buf.WriteByte('=')
+			buf.WriteString(influxTagEscaper.Replace(label.GetValue()))
+		}
+		buf.WriteString(" " + influxField + "=")
+		buf.WriteString(formatFloat(serie.value))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.FormatInt(serie.timestampMs*int64(time.Millisecond), 10))
+		buf.WriteByte('\n')
+	}
+}
+
+// newInfluxHandler serve the metrics from the collector in the InfluxDB line protocol, with the deadline
+// requested by the client like the prometheus metrics handler.
+func newInfluxHandler(collector *SkycoinCollector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metricFamilies, err := gatherCollector(collector, scrapeTimeout(r))
+		if err != nil {
+			log.WithError(err).Errorln("can not gather the metrics")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		buf := new(bytes.Buffer)
+		writeInfluxLines(buf, timeSeriesFromFamilies(metricFamilies, time.Now()))
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if _, err = w.Write(buf.Bytes()); err != nil {
+			log.WithError(err).Errorln("can not write the response")
+		}
+	})
+}
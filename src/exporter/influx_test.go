@@ -0,0 +1,63 @@
+package exporter
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *collectorSuit) TestInfluxLinesEscaping() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	labelName, labelValue := "wallet name", "my,wallet=1"
+	emptyName, emptyValue := "empty", ""
+	labels := []*dto.LabelPair{
+		&dto.LabelPair{Name: &emptyName, Value: &emptyValue},
+		&dto.LabelPair{Name: &labelName, Value: &labelValue},
+	}
+	series := []timeSeries{
+		timeSeries{name: "skycoin_wallet balance", labels: labels, value: 1.5, timestampMs: 1537581604000},
+		timeSeries{name: "skycoin_wallet_nan", value: math.NaN(), timestampMs: 1537581604000},
+	}
+	buf := new(bytes.Buffer)
+
+	// NOTE(denisacostaq@gmail.com): When
+	writeInfluxLines(buf, series)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal("skycoin_wallet\\ balance,wallet\\ name=my\\,wallet\\=1 value=1.5 1537581604000000000\n", buf.String())
+}
+
+func (suite *collectorSuit) TestInfluxHandler() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	recorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newInfluxHandler(collector).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics/influx", nil))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_seq value=58894 1537581604000000000\n")
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_seq_up value=1 ")
+	suite.Contains(recorder.Body.String(), "skycoin_service_up,service=wallet value=1 ")
+}
@@ -2,6 +2,7 @@ package exporter
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/simelo/rextporter/src/client"
@@ -9,43 +10,92 @@ import (
 	"github.com/simelo/rextporter/src/util"
 )
 
+// sample is a metric value with the label values(if any) to be exposed with, timestamp is the upstream
+// time for the value if the metric has one, sa config.Metric.TimestampPath.
+type sample struct {
+	labelValues []string
+	val         float64
+	timestamp   time.Time
+}
+
+// constMetric returns a metric for s, with an explicit timestamp if s has one.
+func constMetric(desc *prometheus.Desc, valueType prometheus.ValueType, s sample) prometheus.Metric {
+	metric := prometheus.MustNewConstMetric(desc, valueType, s.val, s.labelValues...)
+	if s.timestamp.IsZero() {
+		return metric
+	}
+	return prometheus.NewMetricWithTimestamp(s.timestamp, metric)
+}
+
+// statusDesc returns the descriptor for the metric status("_up").
+func statusDesc(metricConf config.Metric, srvConf config.Service) *prometheus.Desc {
+	return prometheus.NewDesc(srvConf.MetricName(metricConf)+"_up", "Says if the same name metric("+srvConf.MetricName(metricConf)+") was success updated, 1 for ok, 0 for failed.", nil, srvConf.MetricConstLabels())
+}
+
+// lastSuccessDesc returns the descriptor for the metric last success update time.
+func lastSuccessDesc(metricConf config.Metric, srvConf config.Service) *prometheus.Desc {
+	return prometheus.NewDesc(srvConf.MetricName(metricConf)+"_last_success_timestamp_seconds", "Unix time in which the same name metric("+srvConf.MetricName(metricConf)+") was success updated for last time.", nil, srvConf.MetricConstLabels())
+}
+
+// dataClients keep a single data client for each service endpoint, so all the metrics in the same
+// endpoint share the request and the decoded document in each scrape.
+type dataClients map[string]*client.DataClient
+
+// metricClient returns a metric client who get the data through the data client for his endpoint.
+func (clients dataClients) metricClient(metricConf config.Metric, srvConf config.Service) (*client.MetricClient, error) {
+	// NOTE(denisacostaq@gmail.com): metrics does not have a request body, so it is not part of the key
+	key := fmt.Sprintf("%s %s %s", srvConf.Name, metricConf.HTTPMethod, srvConf.URIToGetMetric(metricConf))
+	dataClient, ok := clients[key]
+	if !ok {
+		var err error
+		if dataClient, err = client.NewDataClient(metricConf, srvConf); err != nil {
+			return nil, err
+		}
+		clients[key] = dataClient
+	}
+	return client.NewMetricClientFromDataClient(metricConf, dataClient), nil
+}
+
 // CounterMetric has the necessary http client to get and updated value for the counter metric
 type CounterMetric struct {
-	Client           *client.MetricClient
-	lastSuccessValue float64
-	MetricDesc       *prometheus.Desc
-	StatusDesc       *prometheus.Desc
+	Client          *client.MetricClient
+	MetricDesc      *prometheus.Desc
+	StatusDesc      *prometheus.Desc
+	LastSuccessDesc *prometheus.Desc
+	isVec           bool
+	state           *metricState
 }
 
-func createCounter(metricConf config.Metric, srvConf config.Service) (metric CounterMetric, err error) {
+func createCounter(metricConf config.Metric, srvConf config.Service, clients dataClients) (metric CounterMetric, err error) {
 	generalScopeErr := "can not create metric " + metricConf.Name
 	var metricClient *client.MetricClient
-	if metricClient, err = client.NewMetricClient(metricConf, srvConf); err != nil {
+	if metricClient, err = clients.metricClient(metricConf, srvConf); err != nil {
 		errCause := fmt.Sprintln("error creating metric client: ", err.Error())
 		return metric, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
 	metric = CounterMetric{
 		// FIXME(denisacostaq@gmail.com): if you use a duplicated name can panic?
-		Client:     metricClient,
-		MetricDesc: prometheus.NewDesc(srvConf.MetricName(metricConf.Name), metricConf.Options.Description, nil, nil),
-		StatusDesc: prometheus.NewDesc(srvConf.MetricName(metricConf.Name)+"_up", "Says if the same name metric("+srvConf.MetricName(metricConf.Name)+") was success updated, 1 for ok, 0 for failed.", nil, nil),
+		Client:          metricClient,
+		MetricDesc:      prometheus.NewDesc(srvConf.MetricName(metricConf), metricConf.Options.Description, metricConf.LabelNames(), srvConf.MetricConstLabels()),
+		isVec:           metricConf.IsVec() || metricConf.IsStateSet(),
+		StatusDesc:      statusDesc(metricConf, srvConf),
+		LastSuccessDesc: lastSuccessDesc(metricConf, srvConf),
+		state:           newMetricState(metricConf, srvConf),
 	}
 	return metric, err
 }
 
-func createCounters() ([]CounterMetric, error) {
+func createCounters(conf config.RootConfig, clients dataClients) ([]CounterMetric, error) {
 	generalScopeErr := "can not create counters"
-	conf := config.Config() // TODO(denisacostaq@gmail.com): recive conf as parameter
-	metrics := conf.FilterMetricsByType(config.KeyTypeCounter)
-	counters := make([]CounterMetric, len(metrics)*len(conf.Services))
-	for idxService, srvConf := range conf.Services {
-		for idxMetric, metric := range metrics {
-			if counter, err := createCounter(metric, srvConf); err == nil {
-				counters[idxService*len(conf.Services)+idxMetric] = counter
-			} else {
+	var counters []CounterMetric
+	for _, srvConf := range conf.Services {
+		for _, metric := range srvConf.FilterMetricsByType(config.KeyTypeCounter) {
+			counter, err := createCounter(metric, srvConf, clients)
+			if err != nil {
 				errCause := "error creating counter: " + err.Error()
 				return []CounterMetric{}, util.ErrorFromThisScope(errCause, generalScopeErr)
 			}
+			counters = append(counters, counter)
 		}
 	}
 	return counters, nil
@@ -53,41 +103,142 @@ func createCounters() ([]CounterMetric, error) {
 
 // GaugeMetric has the necessary http client to get and updated value for the counter metric
 type GaugeMetric struct {
-	Client           *client.MetricClient
-	lastSuccessValue float64
-	MetricDesc       *prometheus.Desc
-	StatusDesc       *prometheus.Desc
+	Client          *client.MetricClient
+	MetricDesc      *prometheus.Desc
+	StatusDesc      *prometheus.Desc
+	LastSuccessDesc *prometheus.Desc
+	isVec           bool
+	state           *metricState
 }
 
-func createGauge(metricConf config.Metric, srvConf config.Service) (metric GaugeMetric, err error) {
+// createGauge creates a gauge metric, the info metrics are gauges too with the config.InfoMetricSuffix in the name.
+func createGauge(metricConf config.Metric, srvConf config.Service, clients dataClients) (metric GaugeMetric, err error) {
 	generalScopeErr := "can not create metric " + metricConf.Name
 	var metricClient *client.MetricClient
-	if metricClient, err = client.NewMetricClient(metricConf, srvConf); err != nil {
+	if metricClient, err = clients.metricClient(metricConf, srvConf); err != nil {
 		errCause := fmt.Sprintln("error creating metric client: ", err.Error())
 		return metric, util.ErrorFromThisScope(errCause, generalScopeErr)
 	}
 	metric = GaugeMetric{
-		Client:     metricClient,
-		MetricDesc: prometheus.NewDesc(srvConf.MetricName(metricConf.Name), metricConf.Options.Description, nil, nil),
-		StatusDesc: prometheus.NewDesc(srvConf.MetricName(metricConf.Name)+"_up", "Says if the same name metric("+srvConf.MetricName(metricConf.Name)+") was success updated, 1 for ok, 0 for failed.", nil, nil),
+		Client:          metricClient,
+		MetricDesc:      prometheus.NewDesc(srvConf.ExposedMetricName(metricConf), metricConf.Options.Description, metricConf.LabelNames(), srvConf.MetricConstLabels()),
+		isVec:           metricConf.IsVec() || metricConf.IsStateSet(),
+		StatusDesc:      statusDesc(metricConf, srvConf),
+		LastSuccessDesc: lastSuccessDesc(metricConf, srvConf),
+		state:           newMetricState(metricConf, srvConf),
 	}
 	return metric, err
 }
 
-func createGauges() ([]GaugeMetric, error) {
+func createGauges(conf config.RootConfig, clients dataClients) ([]GaugeMetric, error) {
 	generalScopeErr := "can not create gauges"
-	conf := config.Config() // TODO(denisacostaq@gmail.com): recive conf as parameter
-	metrics := conf.FilterMetricsByType(config.KeyTypeGauge)
-	gauges := make([]GaugeMetric, len(metrics))
-	for idxService, srvConf := range conf.Services {
-		for idxMetric, metric := range metrics {
-			gauge, err := createGauge(metric, srvConf)
+	var gauges []GaugeMetric
+	for _, srvConf := range conf.Services {
+		metrics := append(srvConf.FilterMetricsByType(config.KeyTypeGauge), srvConf.FilterMetricsByType(config.KeyTypeInfo)...)
+		for _, metric := range metrics {
+			gauge, err := createGauge(metric, srvConf, clients)
 			if err != nil {
 				errCause := fmt.Sprintln("error creating gauge: ", err.Error())
 				return []GaugeMetric{}, util.ErrorFromThisScope(errCause, generalScopeErr)
 			}
-			gauges[idxService*len(conf.Services)+idxMetric] = gauge
+			gauges = append(gauges, gauge)
 		}
 	}
 	return gauges, nil
 }
+
+// HistogramMetric has the necessary http client to get the values to be observed by the histogram metric
+type HistogramMetric struct {
+	Client          *client.MetricClient
+	Histogram       *prometheus.HistogramVec
+	StatusDesc      *prometheus.Desc
+	LastSuccessDesc *prometheus.Desc
+	state           *metricState
+}
+
+func createHistogram(metricConf config.Metric, srvConf config.Service, clients dataClients) (metric HistogramMetric, err error) {
+	generalScopeErr := "can not create metric " + metricConf.Name
+	var metricClient *client.MetricClient
+	if metricClient, err = clients.metricClient(metricConf, srvConf); err != nil {
+		errCause := fmt.Sprintln("error creating metric client: ", err.Error())
+		return metric, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	metric = HistogramMetric{
+		Client: metricClient,
+		Histogram: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:        srvConf.MetricName(metricConf),
+			Help:        metricConf.Options.Description,
+			ConstLabels: srvConf.MetricConstLabels(),
+			Buckets:     metricConf.HistogramOptions.BucketsBounds(),
+		}, metricConf.LabelNames()),
+		StatusDesc:      statusDesc(metricConf, srvConf),
+		LastSuccessDesc: lastSuccessDesc(metricConf, srvConf),
+		state:           newMetricState(metricConf, srvConf),
+	}
+	return metric, err
+}
+
+func createHistograms(conf config.RootConfig, clients dataClients) ([]HistogramMetric, error) {
+	generalScopeErr := "can not create histograms"
+	var histograms []HistogramMetric
+	for _, srvConf := range conf.Services {
+		for _, metric := range srvConf.FilterMetricsByType(config.KeyTypeHistogram) {
+			histogram, err := createHistogram(metric, srvConf, clients)
+			if err != nil {
+				errCause := fmt.Sprintln("error creating histogram: ", err.Error())
+				return []HistogramMetric{}, util.ErrorFromThisScope(errCause, generalScopeErr)
+			}
+			histograms = append(histograms, histogram)
+		}
+	}
+	return histograms, nil
+}
+
+// SummaryMetric has the necessary http client to get the values to be observed by the summary metric
+type SummaryMetric struct {
+	Client          *client.MetricClient
+	Summary         *prometheus.SummaryVec
+	StatusDesc      *prometheus.Desc
+	LastSuccessDesc *prometheus.Desc
+	state           *metricState
+}
+
+func createSummary(metricConf config.Metric, srvConf config.Service, clients dataClients) (metric SummaryMetric, err error) {
+	generalScopeErr := "can not create metric " + metricConf.Name
+	var metricClient *client.MetricClient
+	if metricClient, err = clients.metricClient(metricConf, srvConf); err != nil {
+		errCause := fmt.Sprintln("error creating metric client: ", err.Error())
+		return metric, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	metric = SummaryMetric{
+		Client: metricClient,
+		Summary: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name:        srvConf.MetricName(metricConf),
+			Help:        metricConf.Options.Description,
+			ConstLabels: srvConf.MetricConstLabels(),
+			Objectives:  metricConf.SummaryOptions.ObjectivesMap(),
+			MaxAge:      metricConf.SummaryOptions.MaxAge,
+			AgeBuckets:  metricConf.SummaryOptions.AgeBuckets,
+		}, metricConf.LabelNames()),
+		StatusDesc:      statusDesc(metricConf, srvConf),
+		LastSuccessDesc: lastSuccessDesc(metricConf, srvConf),
+		state:           newMetricState(metricConf, srvConf),
+	}
+	return metric, err
+}
+
+func createSummaries(conf config.RootConfig, clients dataClients) ([]SummaryMetric, error) {
+	generalScopeErr := "can not create summaries"
+	var summaries []SummaryMetric
+	for _, srvConf := range conf.Services {
+		for _, metric := range srvConf.FilterMetricsByType(config.KeyTypeSummary) {
+			summary, err := createSummary(metric, srvConf, clients)
+			if err != nil {
+				errCause := fmt.Sprintln("error creating summary: ", err.Error())
+				return []SummaryMetric{}, util.ErrorFromThisScope(errCause, generalScopeErr)
+			}
+			summaries = append(summaries, summary)
+		}
+	}
+	return summaries, nil
+}
@@ -0,0 +1,550 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	otlpcollector "github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/collector/metrics/v1"
+	otlpcommon "github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/common/v1"
+	otlpmetrics "github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/metrics/v1"
+	otlpresource "github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/resource/v1"
+	"github.com/simelo/rextporter/src/selfmetrics"
+	"github.com/simelo/rextporter/src/util"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// otlpScopeName is the instrumentation scope for all the metrics.
+	otlpScopeName = "rextporter"
+
+	// NOTE(denisacostaq@gmail.com): the resource attributes for a service, sa
+	// https://github.com/open-telemetry/semantic-conventions
+	otlpAttributeServiceName       = "service.name"
+	otlpAttributeServiceInstanceID = "service.instance.id"
+	otlpAttributeServerAddress     = "server.address"
+
+	// otlpTemporalityCumulative is the AggregationTemporality for the counters and histograms.
+	otlpTemporalityCumulative = int(otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE)
+	// otlpFlagNoRecordedValue is the DataPointFlags for a point without a value, like a stale one(NaN).
+	otlpFlagNoRecordedValue = uint32(otlpmetrics.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK)
+)
+
+// NOTE(denisacostaq@gmail.com): the OTLP messages with the protobuf json mapping tags, only the fields
+// used by rextporter are defined.
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version,omitempty"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64         `json:"startTimeUnixNano,omitempty,string"`
+	TimeUnixNano      uint64         `json:"timeUnixNano,string"`
+	// AsDouble is nil if there is not a recorded value, sa Flags.
+	AsDouble *float64 `json:"asDouble,omitempty"`
+	Flags    uint32   `json:"flags,omitempty"`
+}
+
+// otlpUint64s is encoded as a json array of strings like the protobuf json mapping does for the 64 bits integers.
+type otlpUint64s []uint64
+
+// MarshalJSON encode each value as a string.
+func (values otlpUint64s) MarshalJSON() ([]byte, error) {
+	strValues := make([]string, len(values))
+	for idxValue, value := range values {
+		strValues[idxValue] = strconv.FormatUint(value, 10)
+	}
+	return json.Marshal(strValues)
+}
+
+type otlpHistogramDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64         `json:"startTimeUnixNano,omitempty,string"`
+	TimeUnixNano      uint64         `json:"timeUnixNano,string"`
+	Count             uint64         `json:"count,string"`
+	Sum               float64        `json:"sum"`
+	// BucketCounts has the count for each bucket(not cumulative), with one more bucket than ExplicitBounds for +Inf.
+	BucketCounts   otlpUint64s `json:"bucketCounts"`
+	ExplicitBounds []float64   `json:"explicitBounds"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpHistogramDataPoint `json:"dataPoints"`
+	AggregationTemporality int                      `json:"aggregationTemporality"`
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Gauge       *otlpGauge     `json:"gauge,omitempty"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+// otlpExportRequest is an OTLP ExportMetricsServiceRequest.
+type otlpExportRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+// NOTE(denisacostaq@gmail.com): the protobuf encoding use the generated OTLP messages, sa otlppb.
+
+func otlpProtoAttributes(attributes []otlpKeyValue) []*otlpcommon.KeyValue {
+	protoAttributes := make([]*otlpcommon.KeyValue, 0, len(attributes))
+	for _, attribute := range attributes {
+		protoAttributes = append(protoAttributes, &otlpcommon.KeyValue{
+			Key:   attribute.Key,
+			Value: &otlpcommon.AnyValue{Value: &otlpcommon.AnyValue_StringValue{StringValue: attribute.Value.StringValue}},
+		})
+	}
+	return protoAttributes
+}
+
+func otlpProtoNumberDataPoints(points []otlpNumberDataPoint) []*otlpmetrics.NumberDataPoint {
+	protoPoints := make([]*otlpmetrics.NumberDataPoint, 0, len(points))
+	for _, point := range points {
+		protoPoint := &otlpmetrics.NumberDataPoint{
+			Attributes:        otlpProtoAttributes(point.Attributes),
+			StartTimeUnixNano: point.StartTimeUnixNano,
+			TimeUnixNano:      point.TimeUnixNano,
+			Flags:             point.Flags,
+		}
+		if point.AsDouble != nil {
+			protoPoint.Value = &otlpmetrics.NumberDataPoint_AsDouble{AsDouble: *point.AsDouble}
+		}
+		protoPoints = append(protoPoints, protoPoint)
+	}
+	return protoPoints
+}
+
+func otlpProtoHistogramDataPoints(points []otlpHistogramDataPoint) []*otlpmetrics.HistogramDataPoint {
+	protoPoints := make([]*otlpmetrics.HistogramDataPoint, 0, len(points))
+	for _, point := range points {
+		protoPoints = append(protoPoints, &otlpmetrics.HistogramDataPoint{
+			Attributes:        otlpProtoAttributes(point.Attributes),
+			StartTimeUnixNano: point.StartTimeUnixNano,
+			TimeUnixNano:      point.TimeUnixNano,
+			Count:             point.Count,
+			Sum_:              &otlpmetrics.HistogramDataPoint_Sum{Sum: point.Sum},
+			BucketCounts:      point.BucketCounts,
+			ExplicitBounds:    point.ExplicitBounds,
+		})
+	}
+	return protoPoints
+}
+
+func otlpProtoMetric(metric otlpMetric) *otlpmetrics.Metric {
+	protoMetric := &otlpmetrics.Metric{Name: metric.Name, Description: metric.Description}
+	switch {
+	case metric.Gauge != nil:
+		protoMetric.Data = &otlpmetrics.Metric_Gauge{Gauge: &otlpmetrics.Gauge{
+			DataPoints: otlpProtoNumberDataPoints(metric.Gauge.DataPoints),
+		}}
+	case metric.Sum != nil:
+		protoMetric.Data = &otlpmetrics.Metric_Sum{Sum: &otlpmetrics.Sum{
+			DataPoints:             otlpProtoNumberDataPoints(metric.Sum.DataPoints),
+			AggregationTemporality: otlpmetrics.AggregationTemporality(metric.Sum.AggregationTemporality),
+			IsMonotonic:            metric.Sum.IsMonotonic,
+		}}
+	case metric.Histogram != nil:
+		protoMetric.Data = &otlpmetrics.Metric_Histogram{Histogram: &otlpmetrics.Histogram{
+			DataPoints:             otlpProtoHistogramDataPoints(metric.Histogram.DataPoints),
+			AggregationTemporality: otlpmetrics.AggregationTemporality(metric.Histogram.AggregationTemporality),
+		}}
+	}
+	return protoMetric
+}
+
+// otlpProtoRequest returns request as a protobuf ExportMetricsServiceRequest.
+func otlpProtoRequest(request otlpExportRequest) *otlpcollector.ExportMetricsServiceRequest {
+	protoRequest := &otlpcollector.ExportMetricsServiceRequest{}
+	for _, resourceMetrics := range request.ResourceMetrics {
+		protoResourceMetrics := &otlpmetrics.ResourceMetrics{
+			Resource: &otlpresource.Resource{Attributes: otlpProtoAttributes(resourceMetrics.Resource.Attributes)},
+		}
+		for _, scopeMetrics := range resourceMetrics.ScopeMetrics {
+			protoScopeMetrics := &otlpmetrics.ScopeMetrics{
+				Scope: &otlpcommon.InstrumentationScope{Name: scopeMetrics.Scope.Name, Version: scopeMetrics.Scope.Version},
+			}
+			for _, metric := range scopeMetrics.Metrics {
+				protoScopeMetrics.Metrics = append(protoScopeMetrics.Metrics, otlpProtoMetric(metric))
+			}
+			protoResourceMetrics.ScopeMetrics = append(protoResourceMetrics.ScopeMetrics, protoScopeMetrics)
+		}
+		protoRequest.ResourceMetrics = append(protoRequest.ResourceMetrics, protoResourceMetrics)
+	}
+	return protoRequest
+}
+
+// otlpAttributes returns the labels in m as attributes, the service name and location labels are skipped
+// because they are already in the resource.
+func otlpAttributes(m *dto.Metric) (attributes []otlpKeyValue) {
+	for _, label := range m.GetLabel() {
+		if label.GetName() == config.KeyLabelService || label.GetName() == config.KeyLabelLocation {
+			continue
+		}
+		attributes = append(attributes, otlpKeyValue{Key: label.GetName(), Value: otlpAnyValue{StringValue: label.GetValue()}})
+	}
+	return attributes
+}
+
+// newOTLPNumberDataPoint returns a point for value, a not finite value is sent without a recorded value.
+func newOTLPNumberDataPoint(m *dto.Metric, value float64, startTime, now uint64) otlpNumberDataPoint {
+	point := otlpNumberDataPoint{Attributes: otlpAttributes(m), StartTimeUnixNano: startTime, TimeUnixNano: now}
+	if m.TimestampMs != nil {
+		point.TimeUnixNano = uint64(m.GetTimestampMs()) * uint64(time.Millisecond)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		point.Flags = otlpFlagNoRecordedValue
+	} else {
+		point.AsDouble = &value
+	}
+	return point
+}
+
+// newOTLPHistogramDataPoint returns a point for the histogram in m, the prometheus cumulative buckets are
+// converted to a count for each bucket.
+func newOTLPHistogramDataPoint(m *dto.Metric, startTime, now uint64) otlpHistogramDataPoint {
+	histogram := m.GetHistogram()
+	point := otlpHistogramDataPoint{
+		Attributes:        otlpAttributes(m),
+		StartTimeUnixNano: startTime,
+		TimeUnixNano:      now,
+		Count:             histogram.GetSampleCount(),
+		Sum:               histogram.GetSampleSum(),
+		BucketCounts:      otlpUint64s{},
+		ExplicitBounds:    []float64{},
+	}
+	var previous uint64
+	for _, bucket := range histogram.GetBucket() {
+		if math.IsInf(bucket.GetUpperBound(), 1) {
+			continue
+		}
+		point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+		point.BucketCounts = append(point.BucketCounts, bucket.GetCumulativeCount()-previous)
+		previous = bucket.GetCumulativeCount()
+	}
+	point.BucketCounts = append(point.BucketCounts, histogram.GetSampleCount()-previous)
+	return point
+}
+
+// otlpStartTimes keep the start time for the cumulative series in a service. It is moved forward when a
+// serie value decrease, like a counter reset after a node restart(without the accumulate counter option,
+// sa config.CounterOptions). It is only used from the export goroutine.
+type otlpStartTimes struct {
+	// start is for the series who are not in the previous export, the previous export time or the
+	// exporter creation time for the first one.
+	start   uint64
+	series  map[string]otlpSerieStart
+	current map[string]otlpSerieStart
+}
+
+// otlpSerieStart is the start time for a serie with his value and time in the previous export.
+type otlpSerieStart struct {
+	start uint64
+	value float64
+	time  uint64
+}
+
+func newOTLPStartTimes(start time.Time) *otlpStartTimes {
+	return &otlpStartTimes{
+		start:   uint64(start.UnixNano()),
+		series:  make(map[string]otlpSerieStart),
+		current: make(map[string]otlpSerieStart),
+	}
+}
+
+// otlpSerieKey identify the serie m in the family mf by his name and labels.
+func otlpSerieKey(mf *dto.MetricFamily, m *dto.Metric) string {
+	parts := []string{mf.GetName()}
+	for _, label := range m.GetLabel() {
+		parts = append(parts, label.GetName()+"="+label.GetValue())
+	}
+	return labelsKey(parts)
+}
+
+// startTime returns the start time for the serie with key who has value at the 'at' time. If the value
+// is lower than in the previous export the serie was reset after it, so the previous export time is the
+// new start time.
+func (starts *otlpStartTimes) startTime(key string, value float64, at uint64) uint64 {
+	serie, ok := starts.series[key]
+	if !ok {
+		serie.start = starts.start
+	} else if value < serie.value {
+		serie.start = serie.time
+	}
+	if !math.IsNaN(value) {
+		serie.value, serie.time = value, at
+	}
+	starts.current[key] = serie
+	return serie.start
+}
+
+// exported forget the series who were not in the export made at the 'now' time.
+func (starts *otlpStartTimes) exported(now time.Time) {
+	starts.start = uint64(now.UnixNano())
+	starts.series = starts.current
+	starts.current = make(map[string]otlpSerieStart, len(starts.series))
+}
+
+// otlpMetrics returns the counters, gauges and histograms in families as OTLP metrics, the counters are sent
+// as cumulative and monotonic sums since the start time for each serie, sa otlpStartTimes. The summaries are
+// not supported and they are skipped.
+func otlpMetrics(families []*dto.MetricFamily, starts *otlpStartTimes, now time.Time) (metrics []otlpMetric, points int) {
+	nowNano := uint64(now.UnixNano())
+	for _, mf := range families {
+		metric := otlpMetric{Name: mf.GetName(), Description: mf.GetHelp()}
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			metric.Sum = &otlpSum{AggregationTemporality: otlpTemporalityCumulative, IsMonotonic: true}
+			for _, m := range mf.GetMetric() {
+				point := newOTLPNumberDataPoint(m, m.GetCounter().GetValue(), 0, nowNano)
+				point.StartTimeUnixNano = starts.startTime(otlpSerieKey(mf, m), m.GetCounter().GetValue(), point.TimeUnixNano)
+				metric.Sum.DataPoints = append(metric.Sum.DataPoints, point)
+			}
+			points += len(metric.Sum.DataPoints)
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			metric.Gauge = &otlpGauge{}
+			for _, m := range mf.GetMetric() {
+				value := m.GetGauge().GetValue()
+				if mf.GetType() == dto.MetricType_UNTYPED {
+					value = m.GetUntyped().GetValue()
+				}
+				metric.Gauge.DataPoints = append(metric.Gauge.DataPoints, newOTLPNumberDataPoint(m, value, 0, nowNano))
+			}
+			points += len(metric.Gauge.DataPoints)
+		case dto.MetricType_HISTOGRAM:
+			metric.Histogram = &otlpHistogram{AggregationTemporality: otlpTemporalityCumulative}
+			for _, m := range mf.GetMetric() {
+				point := newOTLPHistogramDataPoint(m, 0, nowNano)
+				point.StartTimeUnixNano = starts.startTime(otlpSerieKey(mf, m), float64(point.Count), nowNano)
+				metric.Histogram.DataPoints = append(metric.Histogram.DataPoints, point)
+			}
+			points += len(metric.Histogram.DataPoints)
+		default:
+			continue
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, points
+}
+
+// otlpResourceAttributes returns the attributes for the service resource, with the service name and location.
+func otlpResourceAttributes(srvConf config.Service) []otlpKeyValue {
+	return []otlpKeyValue{
+		otlpKeyValue{Key: otlpAttributeServiceName, Value: otlpAnyValue{StringValue: srvConf.Name}},
+		otlpKeyValue{Key: otlpAttributeServiceInstanceID, Value: otlpAnyValue{StringValue: fmt.Sprintf("%s:%d", srvConf.Location.Location, srvConf.Port)}},
+		otlpKeyValue{Key: otlpAttributeServerAddress, Value: otlpAnyValue{StringValue: srvConf.Location.Location}},
+	}
+}
+
+// otlpGroup is the metrics for a service and his resource attributes.
+type otlpGroup struct {
+	service    string
+	attributes []otlpKeyValue
+	gatherer   prometheus.Gatherer
+	starts     *otlpStartTimes
+}
+
+// otlpExporter collect the metrics for each service and post them to an OpenTelemetry collector each interval,
+// sa config.OTLPOptions.
+type otlpExporter struct {
+	conf       config.OTLPOptions
+	groups     []otlpGroup
+	httpClient *http.Client
+	stop       chan struct{}
+	stopOnce   sync.Once
+	stopped    chan struct{}
+	started    bool
+}
+
+func newOTLPExporter(conf config.OTLPOptions, rootConf config.RootConfig, collector *SkycoinCollector) (exporter *otlpExporter, err error) {
+	const generalScopeErr = "error creating the otlp exporter"
+	exporter = &otlpExporter{
+		conf:       conf,
+		httpClient: &http.Client{Timeout: conf.TimeoutOrDefault()},
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	for _, serviceName := range collector.services {
+		srvConf, _ := rootConf.FindService(serviceName)
+		registry := prometheus.NewRegistry()
+		if err = registry.Register(scrapeCollector{collector: collector.forService(serviceName), timeout: conf.TimeoutOrDefault()}); err != nil {
+			errCause := fmt.Sprintln("can not register the collector for service ", serviceName, ": ", err.Error())
+			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		exporter.groups = append(exporter.groups, otlpGroup{
+			service:    serviceName,
+			attributes: otlpResourceAttributes(srvConf),
+			gatherer:   registry,
+			starts:     newOTLPStartTimes(time.Now()),
+		})
+	}
+	return exporter, nil
+}
+
+// newOTLPExporterFromConfig returns an otlp exporter for the metrics in collector if the metrics should be sent.
+func newOTLPExporterFromConfig(conf config.RootConfig, collector *SkycoinCollector) (exporter *otlpExporter, err error) {
+	if !conf.OTLP.IsEnabled() {
+		return nil, nil
+	}
+	return newOTLPExporter(conf.OTLP, conf, collector)
+}
+
+// exportRequest collect the metrics for all the services, with a resource for each one.
+func (exporter *otlpExporter) exportRequest() (request otlpExportRequest, points int, err error) {
+	const generalScopeErr = "error collecting metrics for otlp"
+	now := time.Now()
+	request.ResourceMetrics = []otlpResourceMetrics{}
+	for _, group := range exporter.groups {
+		var metricFamilies []*dto.MetricFamily
+		if metricFamilies, err = group.gatherer.Gather(); err != nil {
+			errCause := fmt.Sprintln("can not gather the metrics for service ", group.service, ": ", err.Error())
+			return request, 0, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		metrics, groupPoints := otlpMetrics(metricFamilies, group.starts, now)
+		group.starts.exported(now)
+		points += groupPoints
+		request.ResourceMetrics = append(request.ResourceMetrics, otlpResourceMetrics{
+			Resource: otlpResource{Attributes: group.attributes},
+			ScopeMetrics: []otlpScopeMetrics{
+				otlpScopeMetrics{Scope: otlpScope{Name: otlpScopeName, Version: selfmetrics.Version}, Metrics: metrics},
+			},
+		})
+	}
+	return request, points, nil
+}
+
+// encode returns the request body in the configured encoding and his content type.
+func (exporter *otlpExporter) encode(request otlpExportRequest) (body []byte, contentType string, err error) {
+	if exporter.conf.EncodingOrDefault() == config.KeyOTLPEncodingProtobuf {
+		body, err = otlpProtoRequest(request).Marshal()
+		return body, "application/x-protobuf", err
+	}
+	body, err = json.Marshal(request)
+	return body, "application/json", err
+}
+
+// post send the body to the otlp endpoint and check the response status.
+func (exporter *otlpExporter) post(body []byte, contentType string) (err error) {
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodPost, exporter.conf.URL, bytes.NewReader(body)); err != nil {
+		return err
+	}
+	for name, value := range exporter.conf.Headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", contentType)
+	var resp *http.Response
+	if resp, err = exporter.httpClient.Do(req); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		content, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d from %s: %s", resp.StatusCode, exporter.conf.URL, string(content))
+	}
+	return nil
+}
+
+// export collect the metrics for all the services and send them in a single request.
+func (exporter *otlpExporter) export() (err error) {
+	const generalScopeErr = "error sending metrics to the otlp endpoint"
+	var request otlpExportRequest
+	var points int
+	if request, points, err = exporter.exportRequest(); err != nil {
+		return util.ErrorFromThisScope(err.Error(), generalScopeErr)
+	}
+	var body []byte
+	var contentType string
+	if body, contentType, err = exporter.encode(request); err != nil {
+		errCause := fmt.Sprintln("can not encode the request: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	if err = exporter.post(body, contentType); err != nil {
+		selfmetrics.OTLPDataPoints("failed", points)
+		errCause := fmt.Sprintln("can not send ", points, " data points: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	selfmetrics.OTLPDataPoints("sent", points)
+	return nil
+}
+
+// start sending the metrics each interval in background until stopExporting is called.
+func (exporter *otlpExporter) start() {
+	log.WithFields(log.Fields{"url": exporter.conf.URL, "interval": exporter.conf.IntervalOrDefault()}).Infoln("sending metrics to the otlp endpoint in background")
+	exporter.started = true
+	go func() {
+		defer close(exporter.stopped)
+		export := func() {
+			if err := exporter.export(); err != nil {
+				log.WithError(err).Errorln("can not send the metrics to the otlp endpoint")
+			}
+		}
+		export()
+		ticker := time.NewTicker(exporter.conf.IntervalOrDefault())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				export()
+			case <-exporter.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopExporting stop sending the metrics in background, it is safe to call it many times.
+func (exporter *otlpExporter) stopExporting() {
+	exporter.stopOnce.Do(func() {
+		close(exporter.stop)
+		if exporter.started {
+			<-exporter.stopped
+		}
+	})
+}
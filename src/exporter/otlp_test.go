@@ -0,0 +1,270 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	otlpcollector "github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/collector/metrics/v1"
+	otlpmetrics "github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/metrics/v1"
+	"github.com/stretchr/testify/require"
+)
+
+// otlpReceiver record the bodies and content types of the OTLP requests.
+type otlpReceiver struct {
+	mutex        sync.Mutex
+	bodies       [][]byte
+	contentTypes []string
+	headers      []http.Header
+}
+
+func (receiver *otlpReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.bodies = append(receiver.bodies, body)
+	receiver.contentTypes = append(receiver.contentTypes, r.Header.Get("Content-Type"))
+	receiver.headers = append(receiver.headers, r.Header)
+}
+
+// otlpExporter returns an otlp exporter for a wallet service with the seq and unspents metrics, the otlp
+// options are added to the url.
+func (suite *collectorSuit) otlpExporter(require *require.Assertions, receiverURL, options string) *otlpExporter {
+	tomlConfig := fmt.Sprintf(`
+	[otlp]
+		url = "%s"
+		%s
+`, receiverURL, options) + suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Last block sequence"
+
+	[[services.metrics]]
+		name = "unspents"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/unspents"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Unspent outputs"
+`)
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var exporter *otlpExporter
+	exporter, err = newOTLPExporterFromConfig(config.Config(), collector)
+	require.Nil(err)
+	require.NotNil(exporter)
+	return exporter
+}
+
+func (suite *collectorSuit) TestOTLPJSON() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	require := require.New(suite.T())
+	exporter := suite.otlpExporter(require, server.URL+"/v1/metrics", `headers = { authorization = "Bearer secret" }`)
+
+	// NOTE(denisacostaq@gmail.com): When
+	err := exporter.export()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Nil(err)
+	require.Len(receiver.bodies, 1)
+	suite.Equal("application/json", receiver.contentTypes[0])
+	suite.Equal("Bearer secret", receiver.headers[0].Get("Authorization"))
+	var request struct {
+		ResourceMetrics []struct {
+			Resource struct {
+				Attributes []otlpKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeMetrics []struct {
+				Scope   otlpScope                `json:"scope"`
+				Metrics []map[string]interface{} `json:"metrics"`
+			} `json:"scopeMetrics"`
+		} `json:"resourceMetrics"`
+	}
+	require.Nil(json.Unmarshal(receiver.bodies[0], &request))
+	require.Len(request.ResourceMetrics, 1)
+	resource := request.ResourceMetrics[0]
+	suite.Contains(resource.Resource.Attributes, otlpKeyValue{Key: otlpAttributeServiceName, Value: otlpAnyValue{StringValue: "wallet"}})
+	suite.Contains(resource.Resource.Attributes, otlpKeyValue{Key: otlpAttributeServerAddress, Value: otlpAnyValue{StringValue: "127.0.0.1"}})
+	require.Len(resource.ScopeMetrics, 1)
+	suite.Equal(otlpScopeName, resource.ScopeMetrics[0].Scope.Name)
+	metrics := make(map[string]map[string]interface{})
+	for _, metric := range resource.ScopeMetrics[0].Metrics {
+		metrics[metric["name"].(string)] = metric
+	}
+	require.Contains(metrics, "skycoin_wallet_seq")
+	sum := metrics["skycoin_wallet_seq"]["sum"].(map[string]interface{})
+	suite.Equal(true, sum["isMonotonic"])
+	suite.Equal(float64(otlpTemporalityCumulative), sum["aggregationTemporality"])
+	point := sum["dataPoints"].([]interface{})[0].(map[string]interface{})
+	suite.Equal(float64(58894), point["asDouble"])
+	suite.Equal("1537581604000000000", point["timeUnixNano"])
+	require.Contains(metrics, "skycoin_wallet_unspents")
+	gauge := metrics["skycoin_wallet_unspents"]["gauge"].(map[string]interface{})
+	suite.Equal(float64(38171), gauge["dataPoints"].([]interface{})[0].(map[string]interface{})["asDouble"])
+	require.Contains(metrics, "skycoin_service_up")
+	serviceUp := metrics["skycoin_service_up"]["gauge"].(map[string]interface{})["dataPoints"].([]interface{})[0].(map[string]interface{})
+	suite.NotContains(serviceUp, "attributes")
+}
+
+func (suite *collectorSuit) TestOTLPProtobuf() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	receiver := &otlpReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	require := require.New(suite.T())
+	exporter := suite.otlpExporter(require, server.URL+"/v1/metrics", `encoding = "protobuf"`)
+
+	// NOTE(denisacostaq@gmail.com): When
+	err := exporter.export()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Nil(err)
+	require.Len(receiver.bodies, 1)
+	suite.Equal("application/x-protobuf", receiver.contentTypes[0])
+	var request otlpcollector.ExportMetricsServiceRequest
+	require.Nil(request.Unmarshal(receiver.bodies[0]))
+	require.Len(request.GetResourceMetrics(), 1)
+	resourceMetrics := request.GetResourceMetrics()[0]
+	attributes := make(map[string]string)
+	for _, attribute := range resourceMetrics.GetResource().GetAttributes() {
+		attributes[attribute.GetKey()] = attribute.GetValue().GetStringValue()
+	}
+	suite.Equal("wallet", attributes[otlpAttributeServiceName])
+	suite.Equal(fmt.Sprintf("127.0.0.1:%s", suite.port), attributes[otlpAttributeServiceInstanceID])
+	require.Len(resourceMetrics.GetScopeMetrics(), 1)
+	suite.Equal(otlpScopeName, resourceMetrics.GetScopeMetrics()[0].GetScope().GetName())
+	metrics := make(map[string]*otlpmetrics.Metric)
+	for _, metric := range resourceMetrics.GetScopeMetrics()[0].GetMetrics() {
+		metrics[metric.GetName()] = metric
+	}
+	require.Contains(metrics, "skycoin_wallet_seq")
+	sum := metrics["skycoin_wallet_seq"].GetSum()
+	require.NotNil(sum)
+	suite.Equal(otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.GetAggregationTemporality())
+	suite.True(sum.GetIsMonotonic())
+	require.Len(sum.GetDataPoints(), 1)
+	point := sum.GetDataPoints()[0]
+	suite.Equal(float64(58894), point.GetAsDouble())
+	suite.Equal(uint64(1537581604000000000), point.GetTimeUnixNano())
+	suite.NotZero(point.GetStartTimeUnixNano())
+	require.Contains(metrics, "skycoin_wallet_unspents")
+	suite.NotNil(metrics["skycoin_wallet_unspents"].GetGauge())
+}
+
+func (suite *collectorSuit) TestOTLPFailure() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	require := require.New(suite.T())
+	exporter := suite.otlpExporter(require, server.URL+"/v1/metrics", "")
+
+	// NOTE(denisacostaq@gmail.com): When
+	err := exporter.export()
+	exporter.stopExporting()
+	exporter.stopExporting()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotNil(err)
+}
+
+func (suite *collectorSuit) TestOTLPHistogramDataPoint() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	m := &dto.Metric{
+		Label: []*dto.LabelPair{
+			&dto.LabelPair{Name: proto.String(config.KeyLabelService), Value: proto.String("wallet")},
+			&dto.LabelPair{Name: proto.String("address"), Value: proto.String("2.x.y")},
+		},
+		Histogram: &dto.Histogram{
+			SampleCount: proto.Uint64(6),
+			SampleSum:   proto.Float64(21),
+			Bucket: []*dto.Bucket{
+				&dto.Bucket{UpperBound: proto.Float64(1), CumulativeCount: proto.Uint64(1)},
+				&dto.Bucket{UpperBound: proto.Float64(5), CumulativeCount: proto.Uint64(4)},
+				&dto.Bucket{UpperBound: proto.Float64(math.Inf(1)), CumulativeCount: proto.Uint64(6)},
+			},
+		},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	point := newOTLPHistogramDataPoint(m, 1, 2)
+	stalePoint := newOTLPNumberDataPoint(&dto.Metric{}, math.NaN(), 0, 2)
+	protoPoint := otlpProtoHistogramDataPoints([]otlpHistogramDataPoint{point})[0]
+	staleProtoPoint := otlpProtoNumberDataPoints([]otlpNumberDataPoint{stalePoint})[0]
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal([]otlpKeyValue{otlpKeyValue{Key: "address", Value: otlpAnyValue{StringValue: "2.x.y"}}}, point.Attributes)
+	suite.Equal(uint64(6), point.Count)
+	suite.Equal(float64(21), point.Sum)
+	suite.Equal([]float64{1, 5}, point.ExplicitBounds)
+	suite.Equal(otlpUint64s{1, 3, 2}, point.BucketCounts)
+	suite.Nil(stalePoint.AsDouble)
+	suite.Equal(uint32(otlpFlagNoRecordedValue), stalePoint.Flags)
+	suite.Equal(float64(21), protoPoint.GetSum())
+	suite.Equal([]uint64{1, 3, 2}, protoPoint.GetBucketCounts())
+	suite.Equal([]float64{1, 5}, protoPoint.GetExplicitBounds())
+	suite.Nil(staleProtoPoint.GetValue())
+	suite.Equal(otlpFlagNoRecordedValue, staleProtoPoint.GetFlags())
+}
+
+func (suite *collectorSuit) TestOTLPStartTimeAfterReset() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	start := time.Unix(1537581604, 0)
+	starts := newOTLPStartTimes(start)
+	counter := func(value float64) []*dto.MetricFamily {
+		return []*dto.MetricFamily{&dto.MetricFamily{
+			Name:   proto.String("skycoin_wallet_seq"),
+			Type:   dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{&dto.Metric{Counter: &dto.Counter{Value: proto.Float64(value)}}},
+		}}
+	}
+	export := func(families []*dto.MetricFamily, now time.Time) uint64 {
+		metrics, _ := otlpMetrics(families, starts, now)
+		starts.exported(now)
+		if len(metrics) == 0 {
+			return 0
+		}
+		return metrics[0].Sum.DataPoints[0].StartTimeUnixNano
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	first := export(counter(10), start.Add(time.Minute))
+	increased := export(counter(15), start.Add(2*time.Minute))
+	reset := export(counter(3), start.Add(3*time.Minute))
+	afterReset := export(counter(4), start.Add(4*time.Minute))
+	export(nil, start.Add(5*time.Minute))
+	reappeared := export(counter(1), start.Add(6*time.Minute))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(uint64(start.UnixNano()), first)
+	suite.Equal(uint64(start.UnixNano()), increased)
+	suite.Equal(uint64(start.Add(2*time.Minute).UnixNano()), reset)
+	suite.Equal(reset, afterReset)
+	suite.Equal(uint64(start.Add(5*time.Minute).UnixNano()), reappeared)
+}
@@ -0,0 +1,238 @@
+package exporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simelo/rextporter/src/util"
+	"github.com/simelo/rextporter/src/util/file"
+	log "github.com/sirupsen/logrus"
+)
+
+// persistedSample is a serie value saved in the state file.
+type persistedSample struct {
+	LabelValues []string `json:"labelValues,omitempty"`
+	Value       float64  `json:"value"`
+}
+
+// persistedMetric is the state for a metric saved in the state file, identified by the service and metric names.
+type persistedMetric struct {
+	Service           string            `json:"service"`
+	Metric            string            `json:"metric"`
+	LastSuccess       time.Time         `json:"lastSuccess"`
+	LastSuccessValues []persistedSample `json:"lastSuccessValues,omitempty"`
+	// LastAccumulated, RawValues and Totals are the state for the accumulated counters.
+	LastAccumulated time.Time         `json:"lastAccumulated"`
+	RawValues       []persistedSample `json:"rawValues,omitempty"`
+	Totals          []persistedSample `json:"totals,omitempty"`
+}
+
+// persistedState is the content of the state file.
+type persistedState struct {
+	Metrics []persistedMetric `json:"metrics"`
+}
+
+// persistedSamples returns the samples to be saved, the not finite values are skipped because they can
+// not be encoded as json.
+func persistedSamples(samples []sample) (persisted []persistedSample) {
+	for _, s := range samples {
+		if !math.IsNaN(s.val) && !math.IsInf(s.val, 0) {
+			persisted = append(persisted, persistedSample{LabelValues: s.labelValues, Value: s.val})
+		}
+	}
+	return persisted
+}
+
+// keyedSamples returns the values keyed by the serie label values as samples, sorted by key.
+func keyedSamples(values map[string]float64) (samples []sample) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var labelValues []string
+		if len(key) != 0 {
+			labelValues = strings.Split(key, labelsKeySeparator)
+		}
+		samples = append(samples, sample{labelValues: labelValues, val: values[key]})
+	}
+	return samples
+}
+
+// snapshot returns the state to be saved.
+func (state *metricState) snapshot() persistedMetric {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	persisted := persistedMetric{
+		Service:           state.service,
+		Metric:            state.metric,
+		LastSuccess:       state.lastSuccess,
+		LastSuccessValues: persistedSamples(state.lastSuccessValues),
+	}
+	if state.accumulate {
+		persisted.LastAccumulated = state.lastAccumulated
+		persisted.RawValues = persistedSamples(keyedSamples(state.rawValues))
+		persisted.Totals = persistedSamples(keyedSamples(state.totals))
+	}
+	return persisted
+}
+
+// restore set the state from a saved one, a newer state is not overwritten.
+func (state *metricState) restore(persisted persistedMetric) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if persisted.LastSuccess.After(state.lastSuccess) {
+		state.lastSuccess = persisted.LastSuccess
+		state.lastSuccessValues = make([]sample, len(persisted.LastSuccessValues))
+		for idxSample, s := range persisted.LastSuccessValues {
+			state.lastSuccessValues[idxSample] = sample{labelValues: s.LabelValues, val: s.Value}
+		}
+	}
+	if state.accumulate && persisted.LastAccumulated.After(state.lastAccumulated) {
+		state.lastAccumulated = persisted.LastAccumulated
+		for _, s := range persisted.RawValues {
+			state.rawValues[labelsKey(s.LabelValues)] = s.Value
+		}
+		for _, s := range persisted.Totals {
+			state.totals[labelsKey(s.LabelValues)] = s.Value
+		}
+	}
+}
+
+// stateStore save the metrics state in a file each interval, so it can be restored after a restart.
+type stateStore struct {
+	path     string
+	interval time.Duration
+	states   []*metricState
+	stop     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+	started  bool
+}
+
+func newStateStore(path string, interval time.Duration, states []*metricState) *stateStore {
+	return &stateStore{
+		path:     path,
+		interval: interval,
+		states:   states,
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+}
+
+// load restores the metrics state from the file, if the file does not exist yet there is nothing to restore.
+func (store *stateStore) load() (err error) {
+	const generalScopeErr = "error loading the metrics state"
+	if !file.ExistFile(store.path) {
+		return nil
+	}
+	var content []byte
+	if content, err = ioutil.ReadFile(store.path); err != nil {
+		errCause := fmt.Sprintln("can not read the state file: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	var persisted persistedState
+	if err = json.Unmarshal(content, &persisted); err != nil {
+		errCause := fmt.Sprintln("can not decode the state file: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	persistedMetrics := make(map[string]persistedMetric, len(persisted.Metrics))
+	for _, persistedMetric := range persisted.Metrics {
+		persistedMetrics[persistedMetric.Service+"/"+persistedMetric.Metric] = persistedMetric
+	}
+	for _, state := range store.states {
+		if persistedMetric, ok := persistedMetrics[state.service+"/"+state.metric]; ok {
+			state.restore(persistedMetric)
+		}
+	}
+	return nil
+}
+
+// save writes the metrics state in a temporary file who is renamed to the state file, so the state file
+// is never left half written.
+func (store *stateStore) save() (err error) {
+	const generalScopeErr = "error saving the metrics state"
+	persisted := persistedState{Metrics: make([]persistedMetric, len(store.states))}
+	for idxState, state := range store.states {
+		persisted.Metrics[idxState] = state.snapshot()
+	}
+	var content []byte
+	if content, err = json.MarshalIndent(persisted, "", "  "); err != nil {
+		errCause := fmt.Sprintln("can not encode the state: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	if err = file.CreateFullPathForFile(store.path); err != nil {
+		errCause := fmt.Sprintln("can not create the state folder: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	var tmpFile *os.File
+	if tmpFile, err = ioutil.TempFile(filepath.Dir(store.path), filepath.Base(store.path)+".tmp"); err != nil {
+		errCause := fmt.Sprintln("can not create a temporary file: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	defer func() {
+		if err != nil {
+			if removeErr := os.Remove(tmpFile.Name()); removeErr != nil {
+				log.WithError(removeErr).Errorln("can not remove the temporary state file")
+			}
+		}
+	}()
+	if _, err = tmpFile.Write(content); err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		errCause := fmt.Sprintln("can not write the temporary file: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	if err = os.Rename(tmpFile.Name(), store.path); err != nil {
+		errCause := fmt.Sprintln("can not replace the state file: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return nil
+}
+
+// start saving the state each interval in background until stopSaving is called.
+func (store *stateStore) start() {
+	log.WithFields(log.Fields{"file": store.path, "interval": store.interval}).Infoln("saving the metrics state in background")
+	store.started = true
+	go func() {
+		defer close(store.stopped)
+		ticker := time.NewTicker(store.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := store.save(); err != nil {
+					log.WithError(err).Errorln("can not save the metrics state")
+				}
+			case <-store.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopSaving stop saving the state in background and save it for last time, it is safe to call it many times.
+func (store *stateStore) stopSaving() {
+	store.stopOnce.Do(func() {
+		close(store.stop)
+		if store.started {
+			<-store.stopped
+		}
+		if err := store.save(); err != nil {
+			log.WithError(err).Errorln("can not save the metrics state")
+		}
+	})
+}
@@ -0,0 +1,137 @@
+package exporter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+// stateDir returns a new temporary folder for the state file, who should be removed by the caller
+func stateDir(require *require.Assertions) string {
+	dir, err := ioutil.TempDir("", "rextporter-state")
+	require.Nil(err)
+	return dir
+}
+
+// seqConf returns a service with a gauge named seq in path, the state is persisted in stateFile
+func (suite *collectorSuit) seqConf(stateFile, path string) string {
+	return `
+	[state]
+		persist = true
+		file = "` + stateFile + `"
+` + suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "`+path+`"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+}
+
+func (suite *collectorSuit) TestStateSaveAndLoad() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	dir := stateDir(require)
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "state.json")
+	families := gather(require, suite.seqConf(stateFile, "/blockchain/head/seq"))
+	require.Equal(float64(58894), families["skycoin_wallet_seq"].GetMetric()[0].GetGauge().GetValue())
+	// NOTE(denisacostaq@gmail.com): gather use a fresh collector, so the state is saved from other one
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	registry := prometheus.NewRegistry()
+	require.Nil(registry.Register(collector))
+	_, err = registry.Gather()
+	require.Nil(err)
+	require.Nil(newStateStore(stateFile, time.Minute, collector.states()).save())
+
+	// NOTE(denisacostaq@gmail.com): When
+	require.Nil(config.NewConfigFromRawString(suite.seqConf(stateFile, "/blockchain/head/missing")))
+	var restored *SkycoinCollector
+	restored, err = newSkycoinCollector()
+	require.Nil(err)
+	var store *stateStore
+	store, err = newStateStoreFromConfig(config.Config(), restored)
+	require.Nil(err)
+	require.NotNil(store)
+	registry = prometheus.NewRegistry()
+	require.Nil(registry.Register(restored))
+	metricFamilies, err := registry.Gather()
+	require.Nil(err)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	families = make(map[string]*dto.MetricFamily)
+	for _, mf := range metricFamilies {
+		families[mf.GetName()] = mf
+	}
+	require.Contains(families, "skycoin_wallet_seq")
+	suite.Equal(float64(58894), families["skycoin_wallet_seq"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(float64(0), families["skycoin_wallet_seq_up"].GetMetric()[0].GetGauge().GetValue())
+	require.Contains(families, "skycoin_wallet_seq_last_success_timestamp_seconds")
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(err)
+	suite.Len(files, 1, "the temporary file should be renamed")
+}
+
+func (suite *collectorSuit) TestAccumulatedStateSnapshot() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	metricConf := config.Metric{
+		Name:           "requests",
+		Options:        config.MetricOptions{Type: config.KeyTypeCounter},
+		CounterOptions: config.CounterOptions{Accumulate: true},
+	}
+	srvConf := config.Service{Name: "wallet"}
+	state := newMetricState(metricConf, srvConf)
+	start := time.Now()
+	state.succeeded(state.accumulated([]sample{sample{labelValues: []string{"a", "b"}, val: 10}}, start), start)
+	state.succeeded(state.accumulated([]sample{sample{labelValues: []string{"a", "b"}, val: 4}}, start.Add(time.Second)), start.Add(time.Second))
+	require := require.New(suite.T())
+	content, err := json.Marshal(state.snapshot())
+	require.Nil(err)
+
+	// NOTE(denisacostaq@gmail.com): When
+	var persisted persistedMetric
+	require.Nil(json.Unmarshal(content, &persisted))
+	restored := newMetricState(metricConf, srvConf)
+	restored.restore(persisted)
+	// NOTE(denisacostaq@gmail.com): the upstream was restarted again while rextporter was stopped
+	totals := restored.accumulated([]sample{sample{labelValues: []string{"a", "b"}, val: 1}}, start.Add(time.Minute))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal("wallet", persisted.Service)
+	suite.Equal("requests", persisted.Metric)
+	suite.Equal([]sample{sample{labelValues: []string{"a", "b"}, val: 14}}, restored.staleSamples(start.Add(time.Minute), true))
+	suite.Equal([]sample{sample{labelValues: []string{"a", "b"}, val: 15}}, totals)
+}
+
+func (suite *collectorSuit) TestStateSavedAtStop() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	dir := stateDir(require)
+	defer os.RemoveAll(dir)
+	stateFile := filepath.Join(dir, "nested", "state.json")
+	store := newStateStore(stateFile, time.Hour, nil)
+	require.Nil(store.load(), "a missing state file is not an error")
+	store.start()
+
+	// NOTE(denisacostaq@gmail.com): When
+	store.stopSaving()
+	store.stopSaving()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	content, err := ioutil.ReadFile(stateFile)
+	require.Nil(err)
+	var persisted persistedState
+	suite.Nil(json.Unmarshal(content, &persisted))
+}
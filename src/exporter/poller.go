@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/simelo/rextporter/src/client"
+	log "github.com/sirupsen/logrus"
+)
+
+// poller get the data in background for the services with a poll interval, so the scrapes can be served
+// from the latest data got without hitting the services in each one.
+type poller struct {
+	// mutex protect the results from concurrent updates(polls) and reads(scrapes).
+	mutex   sync.RWMutex
+	results map[*client.DataClient]fetchResult
+	// clients has the data clients for each service with a poll interval, grouped by service name.
+	clients   map[string][]*client.DataClient
+	intervals map[string]time.Duration
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newPoller(dataClients []*client.DataClient) *poller {
+	p := &poller{
+		results:   make(map[*client.DataClient]fetchResult),
+		clients:   make(map[string][]*client.DataClient),
+		intervals: make(map[string]time.Duration),
+		stop:      make(chan struct{}),
+	}
+	for _, dataClient := range dataClients {
+		srvConf := dataClient.Service()
+		if srvConf.PollInterval > 0 {
+			p.clients[srvConf.Name] = append(p.clients[srvConf.Name], dataClient)
+			p.intervals[srvConf.Name] = srvConf.PollInterval
+		}
+	}
+	return p
+}
+
+// polls returns true if the data for dataClient is got in background.
+func (p *poller) polls(dataClient *client.DataClient) bool {
+	_, ok := p.intervals[dataClient.Service().Name]
+	return ok
+}
+
+// result returns the latest data got for dataClient if any.
+func (p *poller) result(dataClient *client.DataClient) (result fetchResult, ok bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	result, ok = p.results[dataClient]
+	return result, ok
+}
+
+// poll get the data for the service serviceName, the clients who are not able to finish before the poll
+// interval are recorded as failed, so the previous data is not served as if it were still updated.
+func (p *poller) poll(serviceName string) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.intervals[serviceName])
+	defer cancel()
+	results := fetchAll(ctx, p.clients[serviceName])
+	now := time.Now()
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, dataClient := range p.clients[serviceName] {
+		result, ok := results[dataClient]
+		if !ok {
+			result = fetchResult{
+				client:    dataClient,
+				err:       fmt.Errorf("unable to get the data before the poll interval: %v", ctx.Err()),
+				fetchedAt: now,
+			}
+		}
+		p.results[dataClient] = result
+	}
+}
+
+// start polling each service in his own goroutine until stop is called.
+func (p *poller) start() {
+	for serviceName, interval := range p.intervals {
+		log.WithFields(log.Fields{"service": serviceName, "interval": interval}).Infoln("polling service in background")
+		go func(serviceName string, interval time.Duration) {
+			p.poll(serviceName)
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					p.poll(serviceName)
+				case <-p.stop:
+					return
+				}
+			}
+		}(serviceName, interval)
+	}
+}
+
+// stopPolling make all the services to stop being polled, it is safe to call it many times.
+func (p *poller) stopPolling() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+	})
+}
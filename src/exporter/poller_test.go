@@ -0,0 +1,168 @@
+package exporter
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *collectorSuit) TestPolledServiceIsServedFromCache() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConfWithOptions(`
+		pollInterval = "1h"
+`, `
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	registry := prometheus.NewRegistry()
+	require.Nil(registry.Register(collector))
+	atomic.StoreInt32(&healthRequests, 0)
+
+	// NOTE(denisacostaq@gmail.com): When
+	var beforePoll, afterPoll []*dto.MetricFamily
+	beforePoll, err = registry.Gather()
+	require.Nil(err)
+	collector.poller.start()
+	defer collector.poller.stopPolling()
+	dataClient := collector.dataClients()[0]
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		if _, ok := collector.poller.result(dataClient); ok {
+			break
+		}
+	}
+	for idx := 0; idx < 3; idx++ {
+		afterPoll, err = registry.Gather()
+		require.Nil(err)
+	}
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(int32(1), atomic.LoadInt32(&healthRequests))
+	before := make(map[string]*dto.MetricFamily)
+	for _, mf := range beforePoll {
+		before[mf.GetName()] = mf
+	}
+	suite.Equal(float64(0), before["skycoin_wallet_seq_up"].GetMetric()[0].GetGauge().GetValue())
+	after := make(map[string]*dto.MetricFamily)
+	for _, mf := range afterPoll {
+		after[mf.GetName()] = mf
+	}
+	suite.Equal(float64(1), after["skycoin_wallet_seq_up"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(float64(58894), after["skycoin_wallet_seq"].GetMetric()[0].GetGauge().GetValue())
+}
+
+func (suite *collectorSuit) TestPolledServiceTimeoutIsFailed() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConfWithOptions(`
+		pollInterval = "100ms"
+`, `
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/slow/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	dataClient := collector.dataClients()[0]
+	collector.poller.results[dataClient] = fetchResult{
+		client:    dataClient,
+		data:      map[string]interface{}{"blockchain": map[string]interface{}{"head": map[string]interface{}{"seq": float64(58894)}}},
+		fetchedAt: time.Now(),
+	}
+	beforePoll := gatherFrom(require, collector)
+
+	// NOTE(denisacostaq@gmail.com): When
+	collector.poller.poll("wallet")
+	afterPoll := gatherFrom(require, collector)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(float64(1), beforePoll["skycoin_wallet_seq_up"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(float64(0), afterPoll["skycoin_wallet_seq_up"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(float64(0), afterPoll["skycoin_service_up"].GetMetric()[0].GetGauge().GetValue())
+	result, ok := collector.poller.result(dataClient)
+	suite.True(ok)
+	suite.NotNil(result.err)
+}
+
+func (suite *collectorSuit) TestPolledHistogramIsObservedOnce() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConfWithOptions(`
+		pollInterval = "1h"
+`, `
+	[[services.metrics]]
+		name = "open_connections"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/open_connections"
+
+		[services.metrics.options]
+			type = "Histogram"
+			description = "Open connections"
+
+		[services.metrics.histogramOptions]
+			exponentialBuckets = [1, 2, 4]
+
+	[[services.metrics]]
+		name = "unconfirmed"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/unconfirmed"
+
+		[services.metrics.options]
+			type = "Summary"
+			description = "Unconfirmed transactions"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	dataClient := collector.dataClients()[0]
+	collector.poller.results[dataClient] = fetchResult{
+		client:    dataClient,
+		data:      map[string]interface{}{"open_connections": float64(8), "blockchain": map[string]interface{}{"unconfirmed": float64(1)}},
+		fetchedAt: time.Now(),
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	var families map[string]*dto.MetricFamily
+	for idx := 0; idx < 4; idx++ {
+		families = gatherFrom(require, collector)
+	}
+	collector.poller.results[dataClient] = fetchResult{
+		client:    dataClient,
+		data:      map[string]interface{}{"open_connections": float64(2), "blockchain": map[string]interface{}{"unconfirmed": float64(3)}},
+		fetchedAt: time.Now().Add(time.Second),
+	}
+	newPollFamilies := gatherFrom(require, collector)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_open_connections")
+	suite.Equal(uint64(1), families["skycoin_wallet_open_connections"].GetMetric()[0].GetHistogram().GetSampleCount())
+	suite.Equal(float64(8), families["skycoin_wallet_open_connections"].GetMetric()[0].GetHistogram().GetSampleSum())
+	require.Contains(families, "skycoin_wallet_unconfirmed")
+	suite.Equal(uint64(1), families["skycoin_wallet_unconfirmed"].GetMetric()[0].GetSummary().GetSampleCount())
+	suite.Equal(uint64(2), newPollFamilies["skycoin_wallet_open_connections"].GetMetric()[0].GetHistogram().GetSampleCount())
+	suite.Equal(float64(10), newPollFamilies["skycoin_wallet_open_connections"].GetMetric()[0].GetHistogram().GetSampleSum())
+	suite.Equal(uint64(2), newPollFamilies["skycoin_wallet_unconfirmed"].GetMetric()[0].GetSummary().GetSampleCount())
+}
@@ -0,0 +1,115 @@
+package exporter
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/expression"
+	log "github.com/sirupsen/logrus"
+)
+
+// probeEndpoint is where the probe handler is served, next to the metrics handler.
+const probeEndpoint = "/probe"
+
+// probeConfig returns a config with just the service requested in the probe, pointing to target
+// ("host:port") if any. The metrics from other services referred in the expressions of his derived
+// metrics are kept too, so they can be evaluated, sa referredServices.
+func probeConfig(conf config.RootConfig, serviceName, target string) (probeConf config.RootConfig, err error) {
+	srvConf, ok := conf.FindService(serviceName)
+	if !ok {
+		return probeConf, fmt.Errorf("service %s is not defined", serviceName)
+	}
+	if len(target) != 0 {
+		var host, strPort string
+		if host, strPort, err = net.SplitHostPort(target); err != nil {
+			return probeConf, fmt.Errorf("target %s should be like host:port: %s", target, err.Error())
+		}
+		var port uint64
+		if port, err = strconv.ParseUint(strPort, 10, 16); err != nil || port == 0 || len(host) == 0 {
+			return probeConf, fmt.Errorf("target %s should be like host:port", target)
+		}
+		srvConf.Location.Location = host
+		srvConf.Port = uint16(port)
+	}
+	// NOTE(denisacostaq@gmail.com): a probe always request the service, it is not polled in background
+	srvConf.PollInterval = 0
+	probeConf = conf
+	probeConf.Services = append([]config.Service{srvConf}, referredServices(conf, srvConf)...)
+	return probeConf, nil
+}
+
+// referredServices returns the other services referred in the expressions of the derived metrics in srvConf,
+// with just the referred metrics and without a poll interval, sa config.RootConfig.DerivedSources.
+func referredServices(conf config.RootConfig, srvConf config.Service) (services []config.Service) {
+	referredMetrics := make(map[string]map[string]bool)
+	for _, metric := range srvConf.FilterMetricsByType(config.KeyTypeDerived) {
+		expr, err := expression.Parse(metric.Expression)
+		if err != nil {
+			// NOTE(denisacostaq@gmail.com): reported in the config validation
+			continue
+		}
+		for _, ref := range expr.Refs() {
+			for _, source := range conf.DerivedSources(srvConf, ref) {
+				if source.Service.Name == srvConf.Name {
+					continue
+				}
+				if referredMetrics[source.Service.Name] == nil {
+					referredMetrics[source.Service.Name] = make(map[string]bool)
+				}
+				referredMetrics[source.Service.Name][source.Metric.Name] = true
+			}
+		}
+	}
+	for _, service := range conf.Services {
+		if len(referredMetrics[service.Name]) == 0 {
+			continue
+		}
+		var metrics []config.Metric
+		for _, metric := range service.Metrics {
+			if referredMetrics[service.Name][metric.Name] {
+				metrics = append(metrics, metric)
+			}
+		}
+		service.Metrics = metrics
+		service.PollInterval = 0
+		services = append(services, service)
+	}
+	return services
+}
+
+// newProbeHandler serve the metrics for a single service in each request, like
+// /probe?service=<name>&target=<host:port>. A fresh collector is created for each probe
+// and the target(optional) replace the service location and port. Only the metrics for the service
+// are exposed, even if other services are requested for his derived metrics.
+func newProbeHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serviceName := r.URL.Query().Get("service")
+		if len(serviceName) == 0 {
+			http.Error(w, "the service parameter is required", http.StatusBadRequest)
+			return
+		}
+		probeConf, err := probeConfig(config.Config(), serviceName, r.URL.Query().Get("target"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var collector *SkycoinCollector
+		if collector, err = newSkycoinCollectorFromConfig(probeConf); err != nil {
+			log.WithError(err).Errorln("can not create the collector for the probe")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		registry := prometheus.NewRegistry()
+		if err = registry.Register(scrapeCollector{collector: collector.forService(serviceName), timeout: scrapeTimeout(r)}); err != nil {
+			log.WithError(err).Errorln("can not register the collector for the probe")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		promhttp.HandlerFor(registry, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,156 @@
+package exporter
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+// probeMetricConf is the metric defined in each service by probeServiceConf
+const probeMetricConf = `
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`
+
+// otherWalletConf returns the other_wallet service listening in port
+func otherWalletConf(port string) string {
+	return fmt.Sprintf(`
+	[[services]]
+		name = "other_wallet"
+		scheme = "http"
+		port = %s
+		basePath = ""
+		authType = "CSRF"
+		tokenHeaderKey = "X-CSRF-Token"
+		genTokenEndpoint = "/api/v1/csrf"
+		tokenKeyFromEndpoint = "csrf_token"
+
+		[services.location]
+			location = "127.0.0.1"
+`, port) + probeMetricConf
+}
+
+// probeServiceConf returns two services(wallet and other_wallet) with the same metric
+func (suite *collectorSuit) probeServiceConf() string {
+	return suite.serviceConf(probeMetricConf) + otherWalletConf("1")
+}
+
+func (suite *collectorSuit) TestProbeService() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.probeServiceConf()))
+	req := httptest.NewRequest("GET", "/probe?service=wallet", nil)
+	recorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newProbeHandler().ServeHTTP(recorder, req)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_seq 58894")
+	suite.NotContains(recorder.Body.String(), "skycoin_other_wallet_seq")
+}
+
+func (suite *collectorSuit) TestProbeServiceWithTarget() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.probeServiceConf()))
+	// NOTE(denisacostaq@gmail.com): other_wallet is configured in a wrong port, the target fix it
+	req := httptest.NewRequest("GET", "/probe?service=other_wallet&target=127.0.0.1:"+suite.port, nil)
+	recorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newProbeHandler().ServeHTTP(recorder, req)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), "skycoin_other_wallet_seq 58894")
+	suite.Contains(recorder.Body.String(), "skycoin_other_wallet_seq_up 1")
+	suite.NotContains(recorder.Body.String(), "skycoin_wallet_seq")
+}
+
+func (suite *collectorSuit) TestProbeInvalidRequest() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.probeServiceConf()))
+	requests := []string{
+		"/probe",
+		"/probe?service=not_defined",
+		"/probe?service=wallet&target=127.0.0.1",
+		"/probe?service=wallet&target=127.0.0.1:port",
+	}
+
+	for _, uri := range requests {
+		recorder := httptest.NewRecorder()
+
+		// NOTE(denisacostaq@gmail.com): When
+		newProbeHandler().ServeHTTP(recorder, httptest.NewRequest("GET", uri, nil))
+
+		// NOTE(denisacostaq@gmail.com): Assert
+		suite.Equal(http.StatusBadRequest, recorder.Code, uri)
+	}
+}
+
+func (suite *collectorSuit) TestProbeInManyServers() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.probeServiceConf()))
+	var servers []*httptest.Server
+	for _, handlerEndpoint := range []string{"/metrics", "/metrics2"} {
+		collector, err := newSkycoinCollector()
+		require.Nil(err)
+		server := httptest.NewServer(newServeMux(collector, handlerEndpoint, ""))
+		defer server.Close()
+		servers = append(servers, server)
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	var codes []int
+	for idxServer, endpoint := range []string{"/metrics", "/metrics2"} {
+		for _, url := range []string{endpoint, endpoint + "/wallet", "/probe?service=wallet"} {
+			resp, err := http.Get(servers[idxServer].URL + url)
+			require.Nil(err)
+			require.Nil(resp.Body.Close())
+			codes = append(codes, resp.StatusCode)
+		}
+	}
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal([]int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK, http.StatusOK}, codes)
+}
+
+func (suite *collectorSuit) TestProbeServiceWithDerivedFromOtherService() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	derivedConf := `
+	[[services.metrics]]
+		name = "wallets_seq"
+		expression = "seq + other_wallet.seq"
+
+		[services.metrics.options]
+			type = "Derived"
+			description = "Head sequence in both wallets"
+`
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.serviceConf(probeMetricConf+derivedConf) + otherWalletConf(suite.port)))
+	req := httptest.NewRequest("GET", "/probe?service=wallet", nil)
+	recorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newProbeHandler().ServeHTTP(recorder, req)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_wallets_seq 117788")
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_wallets_seq_up 1")
+	suite.NotContains(recorder.Body.String(), "skycoin_other_wallet_seq")
+}
@@ -0,0 +1,210 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// pushGroup is the metrics for a service and the Pushgateway url for his grouping key.
+type pushGroup struct {
+	service  string
+	url      string
+	gatherer prometheus.Gatherer
+}
+
+// pusher push the metrics for each service to a Prometheus Pushgateway each interval, sa config.PushOptions.
+type pusher struct {
+	conf       config.PushOptions
+	groups     []pushGroup
+	httpClient *http.Client
+	stop       chan struct{}
+	stopOnce   sync.Once
+	stopped    chan struct{}
+	started    bool
+}
+
+// pushPathComponent encode value to be used in the Pushgateway url, the values with a "/" or empty
+// are base64 encoded as the Pushgateway expect.
+func pushPathComponent(name, value string) string {
+	if len(value) == 0 {
+		return name + "@base64/="
+	}
+	if strings.Contains(value, "/") {
+		return name + "@base64/" + base64.RawURLEncoding.EncodeToString([]byte(value))
+	}
+	return name + "/" + url.PathEscape(value)
+}
+
+// pushGroupURL returns the Pushgateway url for the group of serviceName, like
+// <url>/metrics/job/<job>/service/<service name>/<grouping key label>/<value>...
+func pushGroupURL(conf config.PushOptions, serviceName string) string {
+	groupingKey := make(map[string]string, len(conf.GroupingKey)+1)
+	for name, value := range conf.GroupingKey {
+		groupingKey[name] = value
+	}
+	groupingKey[config.KeyLabelService] = serviceName
+	names := make([]string, 0, len(groupingKey))
+	for name := range groupingKey {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	components := []string{strings.TrimSuffix(conf.URL, "/"), "metrics", pushPathComponent("job", conf.JobOrDefault())}
+	for _, name := range names {
+		components = append(components, pushPathComponent(name, groupingKey[name]))
+	}
+	return strings.Join(components, "/")
+}
+
+func newPusher(conf config.PushOptions, collector *SkycoinCollector) (p *pusher, err error) {
+	const generalScopeErr = "error creating the metrics pusher"
+	p = &pusher{
+		conf:       conf,
+		httpClient: &http.Client{Timeout: conf.TimeoutOrDefault()},
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	for _, serviceName := range collector.services {
+		registry := prometheus.NewRegistry()
+		if err = registry.Register(scrapeCollector{collector: collector.forService(serviceName), timeout: conf.TimeoutOrDefault()}); err != nil {
+			errCause := fmt.Sprintln("can not register the collector for service ", serviceName, ": ", err.Error())
+			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		p.groups = append(p.groups, pushGroup{service: serviceName, url: pushGroupURL(conf, serviceName), gatherer: registry})
+	}
+	return p, nil
+}
+
+// newPusherFromConfig returns a pusher for the metrics in collector if the metrics should be pushed.
+func newPusherFromConfig(conf config.RootConfig, collector *SkycoinCollector) (p *pusher, err error) {
+	if !conf.Push.IsEnabled() {
+		return nil, nil
+	}
+	return newPusher(conf.Push, collector)
+}
+
+// request send a request to the Pushgateway and check the response status.
+func (p *pusher) request(method, groupURL string, body io.Reader) (err error) {
+	var req *http.Request
+	if req, err = http.NewRequest(method, groupURL, body); err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", string(expfmt.FmtProtoDelim))
+	}
+	if len(p.conf.Username) != 0 {
+		req.SetBasicAuth(p.conf.Username, p.conf.Password)
+	}
+	var resp *http.Response
+	if resp, err = p.httpClient.Do(req); err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		content, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status code %d from %s: %s", resp.StatusCode, groupURL, string(content))
+	}
+	return nil
+}
+
+// pushService collect the metrics for a service and replace his group in the Pushgateway with them.
+func (p *pusher) pushService(group pushGroup) (err error) {
+	const generalScopeErr = "error pushing metrics"
+	var metricFamilies []*dto.MetricFamily
+	if metricFamilies, err = group.gatherer.Gather(); err != nil {
+		errCause := fmt.Sprintln("can not gather the metrics: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	buf := new(bytes.Buffer)
+	encoder := expfmt.NewEncoder(buf, expfmt.FmtProtoDelim)
+	for _, mf := range metricFamilies {
+		// NOTE(denisacostaq@gmail.com): the Pushgateway reject the samples with timestamps(sa timestampPath),
+		// the push time is used for them.
+		for _, m := range mf.GetMetric() {
+			m.TimestampMs = nil
+		}
+		if err = encoder.Encode(mf); err != nil {
+			errCause := fmt.Sprintln("can not encode the metrics: ", err.Error())
+			return util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+	}
+	if err = p.request(http.MethodPut, group.url, buf); err != nil {
+		errCause := fmt.Sprintln("can not push the metrics: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return nil
+}
+
+// push the metrics for all the services, a failure in a service does not stop the others.
+func (p *pusher) push() (err error) {
+	for _, group := range p.groups {
+		if groupErr := p.pushService(group); groupErr != nil {
+			log.WithError(groupErr).WithField("service", group.service).Errorln("can not push the service metrics")
+			err = groupErr
+		}
+	}
+	return err
+}
+
+// deleteGroups delete the groups for all the services from the Pushgateway.
+func (p *pusher) deleteGroups() (err error) {
+	const generalScopeErr = "error deleting pushed metrics"
+	for _, group := range p.groups {
+		if groupErr := p.request(http.MethodDelete, group.url, nil); groupErr != nil {
+			log.WithError(groupErr).WithField("service", group.service).Errorln("can not delete the service metrics")
+			err = util.ErrorFromThisScope(groupErr.Error(), generalScopeErr)
+		}
+	}
+	return err
+}
+
+// start pushing the metrics each interval in background until stopPushing is called.
+func (p *pusher) start() {
+	log.WithFields(log.Fields{"url": p.conf.URL, "interval": p.conf.IntervalOrDefault()}).Infoln("pushing metrics in background")
+	p.started = true
+	go func() {
+		defer close(p.stopped)
+		p.push()
+		ticker := time.NewTicker(p.conf.IntervalOrDefault())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.push()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopPushing stop pushing the metrics in background and delete them from the Pushgateway if they should be
+// deleted on shutdown, it is safe to call it many times.
+func (p *pusher) stopPushing() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		if p.started {
+			<-p.stopped
+		}
+		if p.conf.DeleteOnShutdown {
+			if err := p.deleteGroups(); err != nil {
+				log.WithError(err).Errorln("can not delete the pushed metrics")
+			}
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+// pushedRequest is a request received by the pushgateway stand-in, with the pushed metrics(if any) by name.
+type pushedRequest struct {
+	method   string
+	path     string
+	username string
+	password string
+	families map[string]*dto.MetricFamily
+}
+
+// pushgatewayStandIn record the requests received, it reply with status to all of them but the ones with
+// timestamped samples, who are rejected like the Pushgateway does.
+type pushgatewayStandIn struct {
+	mutex    sync.Mutex
+	requests []pushedRequest
+	status   int
+}
+
+func (gateway *pushgatewayStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req := pushedRequest{method: r.Method, path: r.URL.EscapedPath(), families: make(map[string]*dto.MetricFamily)}
+	req.username, req.password, _ = r.BasicAuth()
+	decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+	for {
+		mf := new(dto.MetricFamily)
+		if err := decoder.Decode(mf); err != nil {
+			if err != io.EOF {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			break
+		}
+		for _, m := range mf.GetMetric() {
+			if m.TimestampMs != nil {
+				http.Error(w, "pushed metrics must not have timestamps", http.StatusBadRequest)
+				return
+			}
+		}
+		req.families[mf.GetName()] = mf
+	}
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+	gateway.requests = append(gateway.requests, req)
+	w.WriteHeader(gateway.status)
+}
+
+func (gateway *pushgatewayStandIn) received() []pushedRequest {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+	return append([]pushedRequest(nil), gateway.requests...)
+}
+
+// pushConf returns the probeServiceConf services pushing to gatewayURL
+func (suite *collectorSuit) pushConf(gatewayURL string) string {
+	return fmt.Sprintf(`
+	[push]
+		url = "%s"
+		groupingKey = { instance = "node/1" }
+		username = "rextporter"
+		password = "secret"
+		deleteOnShutdown = true
+`, gatewayURL) + suite.probeServiceConf()
+}
+
+func (suite *collectorSuit) TestPushMetrics() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	gateway := &pushgatewayStandIn{status: http.StatusOK}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.pushConf(server.URL)))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var metricsPusher *pusher
+	metricsPusher, err = newPusherFromConfig(config.Config(), collector)
+	require.Nil(err)
+	require.NotNil(metricsPusher)
+
+	// NOTE(denisacostaq@gmail.com): When
+	err = metricsPusher.push()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	requests := gateway.received()
+	require.Len(requests, 2)
+	suite.Equal(http.MethodPut, requests[0].method)
+	suite.Equal("/metrics/job/rextporter/instance@base64/bm9kZS8x/service/wallet", requests[0].path)
+	suite.Equal("rextporter", requests[0].username)
+	suite.Equal("secret", requests[0].password)
+	require.Contains(requests[0].families, "skycoin_wallet_seq")
+	suite.Equal(float64(58894), requests[0].families["skycoin_wallet_seq"].GetMetric()[0].GetGauge().GetValue())
+	suite.NotContains(requests[0].families, "skycoin_other_wallet_seq")
+	suite.Equal("/metrics/job/rextporter/instance@base64/bm9kZS8x/service/other_wallet", requests[1].path)
+	require.Contains(requests[1].families, "skycoin_other_wallet_seq_up")
+	suite.Equal(float64(0), requests[1].families["skycoin_other_wallet_seq_up"].GetMetric()[0].GetGauge().GetValue())
+}
+
+func (suite *collectorSuit) TestPushTimestampedMetrics() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	gateway := &pushgatewayStandIn{status: http.StatusOK}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(fmt.Sprintf(`
+	[push]
+		url = "%s"
+`, server.URL) + suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var metricsPusher *pusher
+	metricsPusher, err = newPusherFromConfig(config.Config(), collector)
+	require.Nil(err)
+
+	// NOTE(denisacostaq@gmail.com): When
+	err = metricsPusher.push()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	requests := gateway.received()
+	require.Len(requests, 1)
+	require.Contains(requests[0].families, "skycoin_wallet_seq")
+	suite.Equal(float64(58894), requests[0].families["skycoin_wallet_seq"].GetMetric()[0].GetGauge().GetValue())
+	suite.Nil(requests[0].families["skycoin_wallet_seq"].GetMetric()[0].TimestampMs)
+}
+
+func (suite *collectorSuit) TestPushDeleteOnShutdown() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	gateway := &pushgatewayStandIn{status: http.StatusAccepted}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.pushConf(server.URL)))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var metricsPusher *pusher
+	metricsPusher, err = newPusherFromConfig(config.Config(), collector)
+	require.Nil(err)
+
+	// NOTE(denisacostaq@gmail.com): When
+	metricsPusher.start()
+	metricsPusher.stopPushing()
+	metricsPusher.stopPushing()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	requests := gateway.received()
+	require.Len(requests, 4)
+	suite.Equal(http.MethodPut, requests[0].method)
+	suite.Equal(http.MethodPut, requests[1].method)
+	suite.Equal(http.MethodDelete, requests[2].method)
+	suite.Equal("/metrics/job/rextporter/instance@base64/bm9kZS8x/service/wallet", requests[2].path)
+	suite.Equal("rextporter", requests[2].username)
+	suite.Equal(http.MethodDelete, requests[3].method)
+	suite.Equal("/metrics/job/rextporter/instance@base64/bm9kZS8x/service/other_wallet", requests[3].path)
+}
+
+func (suite *collectorSuit) TestPushFailure() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	gateway := &pushgatewayStandIn{status: http.StatusBadRequest}
+	server := httptest.NewServer(gateway)
+	defer server.Close()
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.pushConf(server.URL)))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var metricsPusher *pusher
+	metricsPusher, err = newPusherFromConfig(config.Config(), collector)
+	require.Nil(err)
+
+	// NOTE(denisacostaq@gmail.com): When
+	err = metricsPusher.push()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotNil(err)
+	suite.Len(gateway.received(), 2)
+}
@@ -0,0 +1,219 @@
+package exporter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/relabel"
+	"github.com/simelo/rextporter/src/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// collectorFunc is an unchecked collector(it does not describe his metrics) who collect them with the function.
+type collectorFunc func(ch chan<- prometheus.Metric)
+
+// Describe does not write any descriptor, so the collector is unchecked.
+func (collect collectorFunc) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect writes the metrics from the function.
+func (collect collectorFunc) Collect(ch chan<- prometheus.Metric) {
+	collect(ch)
+}
+
+// relabelService is the relabel rules for a service and his metrics, used to find the service for a serie.
+type relabelService struct {
+	name    string
+	rules   []relabel.Rule
+	metrics []exposedMetric
+}
+
+// relabeler apply the relabel rules for each service and then the global ones to all the collected metrics,
+// sa config.RootConfig.RelabelConfigs. The histograms and summaries are relabeled as a whole, with the family
+// name in "__name__" instead of the _bucket, _sum and _count names.
+type relabeler struct {
+	services []relabelService
+	global   []relabel.Rule
+}
+
+// newRelabeler returns the relabeler for the rules in conf, or nil if there are not relabel rules.
+func newRelabeler(conf config.RootConfig) (r *relabeler, err error) {
+	const generalScopeErr = "error creating the relabeler"
+	r = &relabeler{}
+	hasRules := len(conf.RelabelConfigs) != 0
+	if r.global, err = relabel.CompileAll(conf.RelabelConfigs); err != nil {
+		return nil, util.ErrorFromThisScope(err.Error(), generalScopeErr)
+	}
+	for _, srvConf := range conf.Services {
+		service := relabelService{name: srvConf.Name, metrics: serviceExposedMetrics(srvConf)}
+		if service.rules, err = relabel.CompileAll(srvConf.RelabelConfigs); err != nil {
+			errCause := fmt.Sprintln("can not compile the rules for service ", srvConf.Name, ": ", err.Error())
+			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		hasRules = hasRules || len(service.rules) != 0
+		r.services = append(r.services, service)
+	}
+	if !hasRules {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// serviceRules returns the rules for the service who expose the metric m in the family name: the service with
+// the longest metric name who match, or the service in the "service" label(like in the service health metrics).
+func (r *relabeler) serviceRules(name string, m *dto.Metric) []relabel.Rule {
+	var found *relabelService
+	foundLen := 0
+	for idxService := range r.services {
+		service := &r.services[idxService]
+		for _, metric := range service.metrics {
+			if (name == metric.name || strings.HasPrefix(name, metric.name+"_")) && metric.matches(m) && len(metric.name) > foundLen {
+				found, foundLen = service, len(metric.name)
+			}
+		}
+	}
+	if found != nil {
+		return found.rules
+	}
+	for _, label := range m.GetLabel() {
+		if label.GetName() != config.KeyLabelService {
+			continue
+		}
+		for _, service := range r.services {
+			if service.name == label.GetValue() {
+				return service.rules
+			}
+		}
+	}
+	return nil
+}
+
+// labelPairs returns labels sorted by name.
+func labelPairs(labels map[string]string) (pairs []*dto.LabelPair) {
+	for name, value := range labels {
+		pairs = append(pairs, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].GetName() < pairs[j].GetName()
+	})
+	return pairs
+}
+
+// relabelFamilies returns families with the rules applied to each metric, the metrics are grouped again by the
+// new names. The metrics who get an invalid name, a name of a family with other type or the same name and
+// labels than a previous one are dropped.
+func (r *relabeler) relabelFamilies(families []*dto.MetricFamily) (relabeled []*dto.MetricFamily) {
+	byName := make(map[string]*dto.MetricFamily)
+	seen := make(map[string]bool)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			labels := map[string]string{model.MetricNameLabel: mf.GetName()}
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			rules := append(append([]relabel.Rule(nil), r.serviceRules(mf.GetName(), m)...), r.global...)
+			var keep bool
+			if labels, keep = relabel.Process(labels, rules); !keep {
+				continue
+			}
+			name := labels[model.MetricNameLabel]
+			delete(labels, model.MetricNameLabel)
+			if !model.IsValidMetricName(model.LabelValue(name)) {
+				log.WithFields(log.Fields{"metric": mf.GetName(), "name": name}).Errorln("the relabeled metric name is not valid, dropping it")
+				continue
+			}
+			metric := *m
+			metric.Label = labelPairs(labels)
+			key := name + fmt.Sprint(metric.Label)
+			if seen[key] {
+				log.WithFields(log.Fields{"metric": mf.GetName(), "name": name}).Errorln("the relabeled metric is duplicated, dropping it")
+				continue
+			}
+			family, ok := byName[name]
+			if !ok {
+				family = &dto.MetricFamily{Name: proto.String(name), Help: mf.Help, Type: mf.Type}
+				byName[name] = family
+				relabeled = append(relabeled, family)
+			} else if family.GetType() != mf.GetType() {
+				log.WithFields(log.Fields{"metric": mf.GetName(), "name": name}).Errorln("the relabeled metric type does not match the existing one, dropping it")
+				continue
+			}
+			seen[key] = true
+			family.Metric = append(family.Metric, &metric)
+		}
+	}
+	return relabeled
+}
+
+// familyMetrics returns the metrics in mf ready to be collected again.
+func familyMetrics(mf *dto.MetricFamily) (metrics []prometheus.Metric, err error) {
+	for _, m := range mf.GetMetric() {
+		labelNames := make([]string, len(m.GetLabel()))
+		labelValues := make([]string, len(m.GetLabel()))
+		for idxLabel, label := range m.GetLabel() {
+			labelNames[idxLabel], labelValues[idxLabel] = label.GetName(), label.GetValue()
+		}
+		desc := prometheus.NewDesc(mf.GetName(), mf.GetHelp(), labelNames, nil)
+		var metric prometheus.Metric
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.CounterValue, m.GetCounter().GetValue(), labelValues...)
+		case dto.MetricType_GAUGE:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.GaugeValue, m.GetGauge().GetValue(), labelValues...)
+		case dto.MetricType_UNTYPED:
+			metric, err = prometheus.NewConstMetric(desc, prometheus.UntypedValue, m.GetUntyped().GetValue(), labelValues...)
+		case dto.MetricType_HISTOGRAM:
+			buckets := make(map[float64]uint64, len(m.GetHistogram().GetBucket()))
+			for _, bucket := range m.GetHistogram().GetBucket() {
+				buckets[bucket.GetUpperBound()] = bucket.GetCumulativeCount()
+			}
+			metric, err = prometheus.NewConstHistogram(desc, m.GetHistogram().GetSampleCount(), m.GetHistogram().GetSampleSum(), buckets, labelValues...)
+		case dto.MetricType_SUMMARY:
+			quantiles := make(map[float64]float64, len(m.GetSummary().GetQuantile()))
+			for _, quantile := range m.GetSummary().GetQuantile() {
+				quantiles[quantile.GetQuantile()] = quantile.GetValue()
+			}
+			metric, err = prometheus.NewConstSummary(desc, m.GetSummary().GetSampleCount(), m.GetSummary().GetSampleSum(), quantiles, labelValues...)
+		default:
+			err = fmt.Errorf("metric type %s is not supported", mf.GetType())
+		}
+		if err != nil {
+			return nil, err
+		}
+		if m.TimestampMs != nil {
+			metric = prometheus.NewMetricWithTimestamp(time.Unix(0, m.GetTimestampMs()*int64(time.Millisecond)), metric)
+		}
+		metrics = append(metrics, metric)
+	}
+	return metrics, nil
+}
+
+// collect gather the metrics from collect, relabel them and writes the result to ch.
+func (r *relabeler) collect(ch chan<- prometheus.Metric, collect func(ch chan<- prometheus.Metric)) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(collectorFunc(collect)); err != nil {
+		log.WithError(err).Errorln("can not register the metrics to be relabeled")
+		return
+	}
+	// NOTE(denisacostaq@gmail.com): Gather returns the valid families even if some metrics fail
+	families, err := registry.Gather()
+	if err != nil {
+		log.WithError(err).Errorln("can not gather some metrics to be relabeled")
+	}
+	for _, mf := range r.relabelFamilies(families) {
+		var metrics []prometheus.Metric
+		if metrics, err = familyMetrics(mf); err != nil {
+			log.WithError(err).WithField("metric", mf.GetName()).Errorln("can not collect the relabeled metric")
+			continue
+		}
+		for _, metric := range metrics {
+			ch <- metric
+		}
+	}
+}
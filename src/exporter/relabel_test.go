@@ -0,0 +1,153 @@
+package exporter
+
+import (
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+// relabelConf returns a wallet service with the seq and unspents metrics, the serviceOptions are added to the
+// service section and the globalOptions before it.
+func (suite *collectorSuit) relabelConf(globalOptions, serviceOptions string) string {
+	return globalOptions + suite.serviceConfWithOptions(serviceOptions, `
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Last block sequence"
+
+	[[services.metrics]]
+		name = "unspents"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/unspents"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Unspent outputs"
+`)
+}
+
+func (suite *collectorSuit) TestRelabelGlobal() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.relabelConf(`
+	[[relabelConfigs]]
+		sourceLabels = ["__name__"]
+		regex = ".*_up"
+		action = "drop"
+
+	[[relabelConfigs]]
+		sourceLabels = ["__name__"]
+		regex = "skycoin_wallet_(.*)"
+		targetLabel = "__name__"
+		replacement = "wallet_${1}"
+
+	[[relabelConfigs]]
+		targetLabel = "env"
+		replacement = "production"
+`, "")
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotContains(families, "skycoin_wallet_seq")
+	suite.NotContains(families, "skycoin_wallet_seq_up")
+	suite.NotContains(families, "skycoin_service_up")
+	require.Contains(families, "wallet_seq")
+	seq := families["wallet_seq"]
+	suite.Equal("Last block sequence", seq.GetHelp())
+	require.Len(seq.GetMetric(), 1)
+	suite.Equal(float64(58894), seq.GetMetric()[0].GetCounter().GetValue())
+	suite.Equal(int64(1537581604000), seq.GetMetric()[0].GetTimestampMs())
+	require.Len(seq.GetMetric()[0].GetLabel(), 1)
+	suite.Equal("env", seq.GetMetric()[0].GetLabel()[0].GetName())
+	suite.Equal("production", seq.GetMetric()[0].GetLabel()[0].GetValue())
+	require.Contains(families, "wallet_unspents")
+	suite.Equal(float64(38171), families["wallet_unspents"].GetMetric()[0].GetGauge().GetValue())
+}
+
+func (suite *collectorSuit) TestRelabelService() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.relabelConf(`
+	serviceLabels = true
+`, `
+		[[services.relabelConfigs]]
+			sourceLabels = ["service"]
+			targetLabel = "shard"
+			modulus = 2
+			action = "hashmod"
+
+		[[services.relabelConfigs]]
+			regex = "service"
+			action = "labeldrop"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_seq")
+	labels := make(map[string]string)
+	for _, label := range families["skycoin_seq"].GetMetric()[0].GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	suite.NotContains(labels, config.KeyLabelService)
+	suite.Contains([]string{"0", "1"}, labels["shard"])
+	require.Contains(families, "skycoin_service_up")
+	suite.Len(families["skycoin_service_up"].GetMetric()[0].GetLabel(), 1)
+}
+
+func (suite *collectorSuit) TestRelabelConflicts() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.relabelConf(`
+	[[relabelConfigs]]
+		sourceLabels = ["__name__"]
+		regex = "skycoin_wallet_(seq|unspents)"
+		targetLabel = "__name__"
+		replacement = "skycoin_wallet_value"
+
+	[[relabelConfigs]]
+		sourceLabels = ["__name__"]
+		regex = "skycoin_service_up"
+		targetLabel = "__name__"
+		replacement = "0_not_valid"
+`, "")
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_value")
+	suite.Len(families["skycoin_wallet_value"].GetMetric(), 1)
+	suite.NotContains(families, "skycoin_service_up")
+	suite.Contains(families, "skycoin_wallet_seq_up")
+}
+
+func (suite *collectorSuit) TestRelabelerFromConfig() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	withoutRules := config.RootConfig{Services: []config.Service{config.Service{Name: "wallet"}}}
+	globalRules := config.RootConfig{RelabelConfigs: []relabel.Config{relabel.Config{Regex: "(", TargetLabel: "env"}}}
+	serviceRules := config.RootConfig{
+		Services: []config.Service{config.Service{Name: "wallet", RelabelConfigs: []relabel.Config{relabel.Config{Action: "rename"}}}},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	withoutRulesRelabeler, withoutRulesErr := newRelabeler(withoutRules)
+	_, globalErr := newRelabeler(globalRules)
+	_, serviceErr := newRelabeler(serviceRules)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(withoutRulesErr)
+	suite.Nil(withoutRulesRelabeler)
+	suite.NotNil(globalErr)
+	suite.NotNil(serviceErr)
+}
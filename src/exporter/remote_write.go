@@ -0,0 +1,240 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/prompb"
+	"github.com/simelo/rextporter/src/selfmetrics"
+	"github.com/simelo/rextporter/src/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// remoteWriteVersion is the version of the remote write protocol.
+const remoteWriteVersion = "0.1.0"
+
+// writeRequest returns series as a remote write WriteRequest, each serie is a TimeSeries with the metric
+// name in the __name__ label and a single sample.
+func writeRequest(series []timeSeries) *prompb.WriteRequest {
+	request := &prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(series))}
+	for _, serie := range series {
+		labels := make([]prompb.Label, 0, len(serie.labels)+1)
+		labels = append(labels, prompb.Label{Name: model.MetricNameLabel, Value: serie.name})
+		for _, label := range serie.labels {
+			labels = append(labels, prompb.Label{Name: label.GetName(), Value: label.GetValue()})
+		}
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].Name < labels[j].Name
+		})
+		request.Timeseries = append(request.Timeseries, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{prompb.Sample{Value: serie.value, Timestamp: serie.timestampMs}},
+		})
+	}
+	return request
+}
+
+// remoteWriter collect the metrics each interval and send them to a Prometheus remote write endpoint, sa
+// config.RemoteWriteOptions. The collected series are queued and sent in batches by a single sender, so a
+// slow endpoint never delays the collection.
+type remoteWriter struct {
+	conf       config.RemoteWriteOptions
+	gatherer   prometheus.Gatherer
+	httpClient *http.Client
+	// queue has the series waiting to be sent, it is closed after the collection is stopped.
+	queue     chan timeSeries
+	stop      chan struct{}
+	stopOnce  sync.Once
+	collected chan struct{}
+	sent      chan struct{}
+	started   bool
+}
+
+func newRemoteWriter(conf config.RemoteWriteOptions, collector *SkycoinCollector) (writer *remoteWriter, err error) {
+	const generalScopeErr = "error creating the remote writer"
+	registry := prometheus.NewRegistry()
+	if err = registry.Register(scrapeCollector{collector: collector, timeout: conf.TimeoutOrDefault()}); err != nil {
+		errCause := fmt.Sprintln("can not register the collector: ", err.Error())
+		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	return &remoteWriter{
+		conf:       conf,
+		gatherer:   registry,
+		httpClient: &http.Client{Timeout: conf.TimeoutOrDefault()},
+		queue:      make(chan timeSeries, conf.QueueSizeOrDefault()),
+		stop:       make(chan struct{}),
+		collected:  make(chan struct{}),
+		sent:       make(chan struct{}),
+	}, nil
+}
+
+// newRemoteWriterFromConfig returns a remote writer for the metrics in collector if the metrics should be sent.
+func newRemoteWriterFromConfig(conf config.RootConfig, collector *SkycoinCollector) (writer *remoteWriter, err error) {
+	if !conf.RemoteWrite.IsEnabled() {
+		return nil, nil
+	}
+	return newRemoteWriter(conf.RemoteWrite, collector)
+}
+
+// collect gather the metrics and queue them to be sent, the series who does not fit in the queue are dropped.
+func (writer *remoteWriter) collect() (err error) {
+	const generalScopeErr = "error collecting metrics for remote write"
+	var metricFamilies []*dto.MetricFamily
+	if metricFamilies, err = writer.gatherer.Gather(); err != nil {
+		errCause := fmt.Sprintln("can not gather the metrics: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	dropped := 0
+	for _, serie := range timeSeriesFromFamilies(metricFamilies, time.Now()) {
+		select {
+		case writer.queue <- serie:
+		default:
+			dropped++
+		}
+	}
+	if dropped != 0 {
+		log.WithField("dropped", dropped).Errorln("the remote write queue is full, dropping samples")
+		selfmetrics.RemoteWriteSamples("dropped", dropped)
+	}
+	return nil
+}
+
+// nextBatch wait for series in the queue and returns up to a batch size of them without waiting for
+// more, ok is false if the queue was closed and there are not more series.
+func (writer *remoteWriter) nextBatch() (batch []timeSeries, ok bool) {
+	var serie timeSeries
+	if serie, ok = <-writer.queue; !ok {
+		return nil, false
+	}
+	batch = append(batch, serie)
+	for len(batch) < writer.conf.BatchSizeOrDefault() {
+		select {
+		case serie, ok = <-writer.queue:
+			if !ok {
+				return batch, true
+			}
+			batch = append(batch, serie)
+		default:
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// request send a compressed WriteRequest, recoverable says if it can be retried after an error.
+func (writer *remoteWriter) request(body []byte) (recoverable bool, err error) {
+	var req *http.Request
+	if req, err = http.NewRequest(http.MethodPost, writer.conf.URL, bytes.NewReader(body)); err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	var resp *http.Response
+	if resp, err = writer.httpClient.Do(req); err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		content, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		err = fmt.Errorf("unexpected status code %d from %s: %s", resp.StatusCode, writer.conf.URL, string(content))
+		return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests, err
+	}
+	return false, nil
+}
+
+// send the batch retrying the recoverable errors with an exponential backoff, it returns the last error if
+// the batch can not be sent.
+func (writer *remoteWriter) send(batch []timeSeries) (err error) {
+	const generalScopeErr = "error sending metrics to the remote write endpoint"
+	content, err := proto.Marshal(writeRequest(batch))
+	if err != nil {
+		selfmetrics.RemoteWriteSamples("failed", len(batch))
+		errCause := fmt.Sprintln("can not encode ", len(batch), " samples: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	body := snappy.Encode(nil, content)
+	backoff := writer.conf.RetryBackoffOrDefault()
+	for retries := 0; ; retries++ {
+		var recoverable bool
+		if recoverable, err = writer.request(body); err == nil {
+			selfmetrics.RemoteWriteSamples("sent", len(batch))
+			return nil
+		}
+		if !recoverable || retries == writer.conf.MaxRetriesOrDefault() {
+			selfmetrics.RemoteWriteSamples("failed", len(batch))
+			errCause := fmt.Sprintln("can not send ", len(batch), " samples: ", err.Error())
+			return util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		log.WithError(err).WithField("backoff", backoff).Warnln("can not send the samples, retrying")
+		select {
+		case <-time.After(backoff):
+		case <-writer.stop:
+			// NOTE(denisacostaq@gmail.com): the shutdown should not wait for the backoff
+			selfmetrics.RemoteWriteSamples("failed", len(batch))
+			errCause := fmt.Sprintln("can not send ", len(batch), " samples, the writer was stopped: ", err.Error())
+			return util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		backoff *= 2
+	}
+}
+
+// start collecting the metrics each interval and sending them in background until stopWriting is called.
+func (writer *remoteWriter) start() {
+	log.WithFields(log.Fields{"url": writer.conf.URL, "interval": writer.conf.IntervalOrDefault()}).Infoln("sending metrics to the remote write endpoint in background")
+	writer.started = true
+	go func() {
+		defer close(writer.collected)
+		collect := func() {
+			if err := writer.collect(); err != nil {
+				log.WithError(err).Errorln("can not collect the metrics for remote write")
+			}
+		}
+		collect()
+		ticker := time.NewTicker(writer.conf.IntervalOrDefault())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				collect()
+			case <-writer.stop:
+				return
+			}
+		}
+	}()
+	go func() {
+		defer close(writer.sent)
+		for batch, ok := writer.nextBatch(); ok; batch, ok = writer.nextBatch() {
+			if err := writer.send(batch); err != nil {
+				log.WithError(err).Errorln("can not send the metrics to the remote write endpoint")
+			}
+		}
+	}()
+}
+
+// stopWriting stop collecting the metrics and wait for the queued ones to be sent, the failed requests
+// are not retried after the stop. It is safe to call it many times.
+func (writer *remoteWriter) stopWriting() {
+	writer.stopOnce.Do(func() {
+		close(writer.stop)
+		if writer.started {
+			<-writer.collected
+		}
+		close(writer.queue)
+		if writer.started {
+			<-writer.sent
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package exporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedSerie is a TimeSeries decoded by the remote write receiver, with a single sample.
+type receivedSerie struct {
+	labels      map[string]string
+	value       float64
+	timestampMs int64
+}
+
+// decodeWriteRequest decode a remote write WriteRequest.
+func decodeWriteRequest(content []byte) (series []receivedSerie, err error) {
+	var request prompb.WriteRequest
+	if err = request.Unmarshal(content); err != nil {
+		return nil, err
+	}
+	for _, ts := range request.Timeseries {
+		if len(ts.Samples) != 1 {
+			return nil, fmt.Errorf("expected a single sample, got %d", len(ts.Samples))
+		}
+		serie := receivedSerie{labels: make(map[string]string), value: ts.Samples[0].Value, timestampMs: ts.Samples[0].Timestamp}
+		for _, label := range ts.Labels {
+			serie.labels[label.Name] = label.Value
+		}
+		series = append(series, serie)
+	}
+	return series, nil
+}
+
+// remoteWriteReceiver decode and record the write requests, it reply with the statuses in order and
+// then with 200.
+type remoteWriteReceiver struct {
+	mutex    sync.Mutex
+	requests [][]receivedSerie
+	statuses []int
+}
+
+func (receiver *remoteWriteReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Encoding") != "snappy" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+		http.Error(w, "unexpected encoding", http.StatusUnsupportedMediaType)
+		return
+	}
+	compressed, err := ioutil.ReadAll(r.Body)
+	var content []byte
+	if err == nil {
+		content, err = snappy.Decode(nil, compressed)
+	}
+	var series []receivedSerie
+	if err == nil {
+		series, err = decodeWriteRequest(content)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	receiver.requests = append(receiver.requests, series)
+	status := http.StatusOK
+	if len(receiver.statuses) != 0 {
+		status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (receiver *remoteWriteReceiver) received() [][]receivedSerie {
+	receiver.mutex.Lock()
+	defer receiver.mutex.Unlock()
+	return append([][]receivedSerie(nil), receiver.requests...)
+}
+
+// remoteWriter returns a remote writer for a wallet service with the seq and unspents metrics, the
+// remote write options are added to the url.
+func (suite *collectorSuit) remoteWriter(require *require.Assertions, receiverURL, options string) *remoteWriter {
+	tomlConfig := fmt.Sprintf(`
+	[remoteWrite]
+		url = "%s"
+		%s
+`, receiverURL, options) + suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Last block sequence"
+
+	[[services.metrics]]
+		name = "unspents"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/unspents"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Unspent outputs"
+`)
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var writer *remoteWriter
+	writer, err = newRemoteWriterFromConfig(config.Config(), collector)
+	require.Nil(err)
+	require.NotNil(writer)
+	return writer
+}
+
+func (suite *collectorSuit) TestRemoteWrite() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	receiver := &remoteWriteReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	require := require.New(suite.T())
+	writer := suite.remoteWriter(require, server.URL, "batchSize = 2")
+
+	// NOTE(denisacostaq@gmail.com): When
+	writer.start()
+	writer.stopWriting()
+	writer.stopWriting()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	requests := receiver.received()
+	require.True(len(requests) > 1)
+	series := make(map[string]receivedSerie)
+	for _, request := range requests {
+		suite.True(len(request) <= 2)
+		for _, serie := range request {
+			series[serie.labels["__name__"]] = serie
+		}
+	}
+	require.Contains(series, "skycoin_wallet_seq")
+	suite.Equal(float64(58894), series["skycoin_wallet_seq"].value)
+	suite.Equal(int64(1537581604000), series["skycoin_wallet_seq"].timestampMs)
+	require.Contains(series, "skycoin_wallet_unspents")
+	suite.Equal(float64(38171), series["skycoin_wallet_unspents"].value)
+	require.Contains(series, "skycoin_service_up")
+	suite.Equal(map[string]string{"__name__": "skycoin_service_up", "service": "wallet"}, series["skycoin_service_up"].labels)
+	suite.Equal(float64(1), series["skycoin_service_up"].value)
+}
+
+func (suite *collectorSuit) TestRemoteWriteRetry() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	receiver := &remoteWriteReceiver{statuses: []int{http.StatusInternalServerError, http.StatusTooManyRequests}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	require := require.New(suite.T())
+	writer := suite.remoteWriter(require, server.URL, `retryBackoff = "1ms"`)
+	batch := []timeSeries{timeSeries{name: "skycoin_wallet_seq", value: 58894, timestampMs: 1537581604000}}
+
+	// NOTE(denisacostaq@gmail.com): When
+	err := writer.send(batch)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	requests := receiver.received()
+	require.Len(requests, 3)
+	suite.Equal([]receivedSerie{receivedSerie{labels: map[string]string{"__name__": "skycoin_wallet_seq"}, value: 58894, timestampMs: 1537581604000}}, requests[2])
+}
+
+func (suite *collectorSuit) TestRemoteWriteFailure() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	receiver := &remoteWriteReceiver{statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusBadRequest}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	require := require.New(suite.T())
+	writer := suite.remoteWriter(require, server.URL, `retryBackoff = "1ms"
+		maxRetries = 2`)
+	batch := []timeSeries{timeSeries{name: "skycoin_wallet_seq", value: 58894}}
+
+	// NOTE(denisacostaq@gmail.com): When
+	retriesErr := writer.send(batch)
+	notRecoverableErr := writer.send(batch)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotNil(retriesErr)
+	suite.NotNil(notRecoverableErr)
+	suite.Len(receiver.received(), 4)
+}
+
+func (suite *collectorSuit) TestRemoteWriteStopDuringBackoff() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	receiver := &remoteWriteReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+	require := require.New(suite.T())
+	writer := suite.remoteWriter(require, server.URL, `retryBackoff = "1h"
+		maxRetries = 1`)
+	batch := []timeSeries{timeSeries{name: "skycoin_wallet_seq", value: 58894}}
+
+	// NOTE(denisacostaq@gmail.com): When
+	start := time.Now()
+	time.AfterFunc(50*time.Millisecond, writer.stopWriting)
+	err := writer.send(batch)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotNil(err)
+	suite.True(time.Since(start) < time.Second)
+	suite.Len(receiver.received(), 1)
+}
+
+func (suite *collectorSuit) TestRemoteWriteQueueFull() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	writer := suite.remoteWriter(require, "http://127.0.0.1:1/api/v1/write", `queueSize = 2
+		batchSize = 1`)
+
+	// NOTE(denisacostaq@gmail.com): When
+	err := writer.collect()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Len(writer.queue, 2)
+	batch, ok := writer.nextBatch()
+	suite.True(ok)
+	suite.Len(batch, 1)
+}
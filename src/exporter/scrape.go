@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/client"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultScrapeTimeout is used if the scrape request does not say how long prometheus will wait.
+	defaultScrapeTimeout = 10 * time.Second
+	// scrapeTimeoutOffset is subtracted from the prometheus scrape timeout to have time to write the response.
+	scrapeTimeoutOffset = 500 * time.Millisecond
+	// maxConcurrentRequests is how many endpoints can be requested to the services at the same time.
+	maxConcurrentRequests = 8
+	// scrapeTimeoutHeader is the header in which prometheus send the scrape timeout in seconds.
+	scrapeTimeoutHeader = "X-Prometheus-Scrape-Timeout-Seconds"
+)
+
+// fetchResult is the json document(or error) got from a data client.
+type fetchResult struct {
+	client    *client.DataClient
+	data      interface{}
+	err       error
+	fetchedAt time.Time
+	// duration is how long it takes to get and decode the document.
+	duration time.Duration
+	response client.ResponseInfo
+}
+
+// fetchAll get the data for all the clients using a bounded pool of workers. Only the clients who finish
+// before ctx is done are in the returned results.
+func fetchAll(ctx context.Context, clients []*client.DataClient) (results map[*client.DataClient]fetchResult) {
+	jobs := make(chan *client.DataClient, len(clients))
+	for _, dataClient := range clients {
+		jobs <- dataClient
+	}
+	close(jobs)
+	// NOTE(denisacostaq@gmail.com): buffered so workers never block if the deadline was reached
+	resultsCh := make(chan fetchResult, len(clients))
+	workers := maxConcurrentRequests
+	if len(clients) < workers {
+		workers = len(clients)
+	}
+	for idxWorker := 0; idxWorker < workers; idxWorker++ {
+		go func() {
+			for dataClient := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				start := time.Now()
+				data, response, err := dataClient.GetDataWithResponseInfo(ctx)
+				resultsCh <- fetchResult{
+					client:    dataClient,
+					data:      data,
+					err:       err,
+					fetchedAt: time.Now(),
+					duration:  time.Since(start),
+					response:  response,
+				}
+			}
+		}()
+	}
+	results = make(map[*client.DataClient]fetchResult, len(clients))
+	for len(results) < len(clients) {
+		select {
+		case result := <-resultsCh:
+			results[result.client] = result
+		case <-ctx.Done():
+			log.WithField("pending", len(clients)-len(results)).Errorln("scrape deadline reached")
+			return results
+		}
+	}
+	return results
+}
+
+// scrapeTimeout returns how long the scrape can take, from the prometheus header if any.
+func scrapeTimeout(r *http.Request) time.Duration {
+	header := r.Header.Get(scrapeTimeoutHeader)
+	if len(header) == 0 {
+		return defaultScrapeTimeout
+	}
+	seconds, err := strconv.ParseFloat(header, 64)
+	if err != nil || seconds <= 0 {
+		log.WithField("header", header).Errorln("invalid scrape timeout, using the default one")
+		return defaultScrapeTimeout
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+	if timeout > 2*scrapeTimeoutOffset {
+		timeout -= scrapeTimeoutOffset
+	}
+	return timeout
+}
+
+// scrapeCollector is the collector view for a single scrape request, with his own deadline.
+type scrapeCollector struct {
+	collector *SkycoinCollector
+	timeout   time.Duration
+}
+
+// Describe writes all the descriptors from the wrapped collector.
+func (sc scrapeCollector) Describe(ch chan<- *prometheus.Desc) {
+	sc.collector.Describe(ch)
+}
+
+// Collect update the wrapped collector values honoring the scrape timeout.
+func (sc scrapeCollector) Collect(ch chan<- prometheus.Metric) {
+	sc.collector.collectWithTimeout(ch, sc.timeout)
+}
+
+// gatherCollector collect the metrics from the collector honoring the timeout, sa scrapeCollector.
+func gatherCollector(collector *SkycoinCollector, timeout time.Duration) ([]*dto.MetricFamily, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(scrapeCollector{collector: collector, timeout: timeout}); err != nil {
+		return nil, err
+	}
+	return registry.Gather()
+}
+
+// newMetricsHandler serve the metrics from the collector with the deadline requested by prometheus,
+// and from the gatherers if any(for example the default registry).
+func newMetricsHandler(collector *SkycoinCollector, gatherers ...prometheus.Gatherer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		registry := prometheus.NewRegistry()
+		if err := registry.Register(scrapeCollector{collector: collector, timeout: scrapeTimeout(r)}); err != nil {
+			log.WithError(err).Errorln("can not register the collector")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		promhttp.HandlerFor(append(prometheus.Gatherers{registry}, gatherers...), promhttp.HandlerOpts{}).ServeHTTP(w, r)
+	})
+}
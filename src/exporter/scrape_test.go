@@ -0,0 +1,177 @@
+package exporter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *collectorSuit) TestScrapeTimeoutFromHeader() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	withHeader := httptest.NewRequest("GET", "/metrics", nil)
+	withHeader.Header.Set(scrapeTimeoutHeader, "5")
+	withoutHeader := httptest.NewRequest("GET", "/metrics", nil)
+	withInvalidHeader := httptest.NewRequest("GET", "/metrics", nil)
+	withInvalidHeader.Header.Set(scrapeTimeoutHeader, "five")
+
+	// NOTE(denisacostaq@gmail.com): When
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(5*time.Second-scrapeTimeoutOffset, scrapeTimeout(withHeader))
+	suite.Equal(defaultScrapeTimeout, scrapeTimeout(withoutHeader))
+	suite.Equal(defaultScrapeTimeout, scrapeTimeout(withInvalidHeader))
+}
+
+func (suite *collectorSuit) TestSlowMetricIsReportedAsFailed() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "slow_seq"
+		url = "/api/v1/slow/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Slow head sequence"
+
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	registry := prometheus.NewRegistry()
+	require.Nil(registry.Register(scrapeCollector{collector: collector, timeout: 200 * time.Millisecond}))
+
+	// NOTE(denisacostaq@gmail.com): When
+	start := time.Now()
+	var metricFamilies []*dto.MetricFamily
+	metricFamilies, err = registry.Gather()
+	elapsed := time.Since(start)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Nil(err)
+	suite.True(elapsed < time.Second)
+	families := make(map[string]*dto.MetricFamily)
+	for _, mf := range metricFamilies {
+		families[mf.GetName()] = mf
+	}
+	require.Contains(families, "skycoin_wallet_slow_seq_up")
+	suite.Equal(float64(0), families["skycoin_wallet_slow_seq_up"].GetMetric()[0].GetGauge().GetValue())
+	require.Contains(families, "skycoin_wallet_seq_up")
+	suite.Equal(float64(1), families["skycoin_wallet_seq_up"].GetMetric()[0].GetGauge().GetValue())
+	suite.Equal(float64(58894), families["skycoin_wallet_seq"].GetMetric()[0].GetGauge().GetValue())
+}
+
+func (suite *collectorSuit) TestMetricsHandler() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	req.Header.Set(scrapeTimeoutHeader, "2")
+	recorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newMetricsHandler(collector, prometheus.DefaultGatherer).ServeHTTP(recorder, req)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_seq 58894")
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_seq_up 1")
+}
+
+func (suite *collectorSuit) TestMetricsHandlerWithSelfMetrics() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+
+	[[services.metrics]]
+		name = "missing"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/missing"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Not existing value"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	recorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newMetricsHandler(collector, prometheus.DefaultGatherer, selfmetrics.Registry()).ServeHTTP(recorder, req)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), "skycoin_wallet_seq 58894")
+	suite.Contains(recorder.Body.String(), `rextporter_upstream_requests_total{endpoint="/api/v1/health",service="wallet",status="200"}`)
+	suite.Contains(recorder.Body.String(), `rextporter_path_lookup_failures_total{endpoint="/api/v1/health",service="wallet"}`)
+	suite.Contains(recorder.Body.String(), "rextporter_collection_duration_seconds_count")
+	suite.Contains(recorder.Body.String(), "rextporter_build_info{")
+	suite.Contains(recorder.Body.String(), "rextporter_config_last_load_timestamp_seconds")
+}
+
+func (suite *collectorSuit) TestServiceMetricsHandler() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.probeServiceConf()))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	walletRecorder := httptest.NewRecorder()
+	otherWalletRecorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newMetricsHandler(collector.forService("wallet")).ServeHTTP(walletRecorder, httptest.NewRequest("GET", "/metrics/wallet", nil))
+	newMetricsHandler(collector.forService("other_wallet")).ServeHTTP(otherWalletRecorder, httptest.NewRequest("GET", "/metrics/other_wallet", nil))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, walletRecorder.Code)
+	suite.Contains(walletRecorder.Body.String(), "skycoin_wallet_seq 58894")
+	suite.Contains(walletRecorder.Body.String(), `skycoin_service_up{service="wallet"} 1`)
+	suite.NotContains(walletRecorder.Body.String(), "other_wallet")
+	suite.Equal(http.StatusOK, otherWalletRecorder.Code)
+	suite.Contains(otherWalletRecorder.Body.String(), "skycoin_other_wallet_seq_up 0")
+	suite.Contains(otherWalletRecorder.Body.String(), `skycoin_service_up{service="other_wallet"} 0`)
+	suite.NotContains(otherWalletRecorder.Body.String(), "skycoin_wallet_seq")
+	suite.NotContains(otherWalletRecorder.Body.String(), `service="wallet"`)
+}
@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// timeSeries is a single value from a gathered metric family, like the series in the prometheus text
+// format: the histograms and summaries are split in the _bucket, _sum and _count(or quantile) series.
+type timeSeries struct {
+	name string
+	// labels are sorted by name.
+	labels      []*dto.LabelPair
+	value       float64
+	timestampMs int64
+	// timestamped says if the metric has his own timestamp, if not timestampMs is the gather time.
+	timestamped bool
+}
+
+// newTimeSeries returns a serie for m with the extra label(if extraName is not empty).
+func newTimeSeries(name string, m *dto.Metric, extraName, extraValue string, value float64, timestampMs int64) timeSeries {
+	labels := make([]*dto.LabelPair, 0, len(m.GetLabel())+1)
+	labels = append(labels, m.GetLabel()...)
+	if len(extraName) != 0 {
+		labels = append(labels, &dto.LabelPair{Name: &extraName, Value: &extraValue})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+	return timeSeries{name: name, labels: labels, value: value, timestampMs: timestampMs}
+}
+
+// formatFloat format a bucket bound or a quantile like prometheus does, for example "+Inf".
+func formatFloat(val float64) string {
+	return strconv.FormatFloat(val, 'g', -1, 64)
+}
+
+// timeSeriesFromFamilies returns the series in the gathered families, the series without a timestamp get the
+// now one.
+func timeSeriesFromFamilies(families []*dto.MetricFamily, now time.Time) (series []timeSeries) {
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			timestampMs := nowMs
+			if m.TimestampMs != nil {
+				timestampMs = m.GetTimestampMs()
+			}
+			seriesStart := len(series)
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				series = append(series, newTimeSeries(mf.GetName(), m, "", "", m.GetCounter().GetValue(), timestampMs))
+			case dto.MetricType_GAUGE:
+				series = append(series, newTimeSeries(mf.GetName(), m, "", "", m.GetGauge().GetValue(), timestampMs))
+			case dto.MetricType_UNTYPED:
+				series = append(series, newTimeSeries(mf.GetName(), m, "", "", m.GetUntyped().GetValue(), timestampMs))
+			case dto.MetricType_HISTOGRAM:
+				histogram := m.GetHistogram()
+				hasInf := false
+				for _, bucket := range histogram.GetBucket() {
+					hasInf = hasInf || math.IsInf(bucket.GetUpperBound(), 1)
+					series = append(series, newTimeSeries(mf.GetName()+"_bucket", m, model.BucketLabel, formatFloat(bucket.GetUpperBound()), float64(bucket.GetCumulativeCount()), timestampMs))
+				}
+				if !hasInf {
+					series = append(series, newTimeSeries(mf.GetName()+"_bucket", m, model.BucketLabel, formatFloat(math.Inf(1)), float64(histogram.GetSampleCount()), timestampMs))
+				}
+				series = append(series, newTimeSeries(mf.GetName()+"_sum", m, "", "", histogram.GetSampleSum(), timestampMs))
+				series = append(series, newTimeSeries(mf.GetName()+"_count", m, "", "", float64(histogram.GetSampleCount()), timestampMs))
+			case dto.MetricType_SUMMARY:
+				summary := m.GetSummary()
+				for _, quantile := range summary.GetQuantile() {
+					series = append(series, newTimeSeries(mf.GetName(), m, model.QuantileLabel, formatFloat(quantile.GetQuantile()), quantile.GetValue(), timestampMs))
+				}
+				series = append(series, newTimeSeries(mf.GetName()+"_sum", m, "", "", summary.GetSampleSum(), timestampMs))
+				series = append(series, newTimeSeries(mf.GetName()+"_count", m, "", "", float64(summary.GetSampleCount()), timestampMs))
+			}
+			for idxSerie := seriesStart; idxSerie < len(series); idxSerie++ {
+				series[idxSerie].timestamped = m.TimestampMs != nil
+			}
+		}
+	}
+	return series
+}
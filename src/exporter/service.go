@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/simelo/rextporter/src/client"
+	"github.com/simelo/rextporter/src/config"
+)
+
+// serviceHealth has the descriptors for the health metrics exposed for each service.
+type serviceHealth struct {
+	up            *prometheus.Desc
+	duration      *prometheus.Desc
+	responseSize  *prometheus.Desc
+	statusCode    *prometheus.Desc
+	failedMetrics *prometheus.Desc
+}
+
+func newServiceHealth(namespace string) serviceHealth {
+	return serviceHealth{
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "service", "up"),
+			"Says if all the endpoints in the service were success requested, 1 for ok, 0 for failed.",
+			[]string{"service"}, nil),
+		duration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "service", "scrape_duration_seconds"),
+			"Time spent getting and decoding the data from all the endpoints in the service.",
+			[]string{"service"}, nil),
+		responseSize: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "service", "response_size_bytes"),
+			"Size of the responses got from all the endpoints in the service.",
+			[]string{"service"}, nil),
+		statusCode: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "service", "http_status_code"),
+			"Highest http status code got from the endpoints in the service, 0 if some of them does not respond.",
+			[]string{"service"}, nil),
+		failedMetrics: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "service", "failed_metrics"),
+			"How many metrics in the service were not success updated.",
+			[]string{"service"}, nil),
+	}
+}
+
+// serviceNames returns the name of all the configured services.
+func serviceNames(conf config.RootConfig) (names []string) {
+	for _, srvConf := range conf.Services {
+		names = append(names, srvConf.Name)
+	}
+	return names
+}
+
+// describe writes the descriptors for the service health metrics.
+func (health serviceHealth) describe(ch chan<- *prometheus.Desc) {
+	ch <- health.up
+	ch <- health.duration
+	ch <- health.responseSize
+	ch <- health.statusCode
+	ch <- health.failedMetrics
+}
+
+// collectServices writes the health metrics for each service from the results got for his endpoints,
+// failedMetrics has how many metrics fail for each service.
+func (collector *SkycoinCollector) collectServices(ch chan<- prometheus.Metric, results map[*client.DataClient]fetchResult, failedMetrics map[string]int) {
+	endpoints := make(map[string][]*client.DataClient)
+	for _, dataClient := range collector.dataClients() {
+		serviceName := dataClient.Service().Name
+		endpoints[serviceName] = append(endpoints[serviceName], dataClient)
+	}
+	for _, serviceName := range collector.services {
+		up, statusCode, responded := 1.0, 0, true
+		var duration float64
+		var responseSize int
+		for _, dataClient := range endpoints[serviceName] {
+			result, ok := results[dataClient]
+			if !ok || result.err != nil {
+				up = 0
+			}
+			if !ok || result.response.StatusCode == 0 {
+				responded = false
+			}
+			if !ok {
+				continue
+			}
+			duration += result.duration.Seconds()
+			responseSize += result.response.Size
+			if result.response.StatusCode > statusCode {
+				statusCode = result.response.StatusCode
+			}
+		}
+		if !responded {
+			statusCode = 0
+		}
+		ch <- prometheus.MustNewConstMetric(collector.serviceHealth.up, prometheus.GaugeValue, up, serviceName)
+		ch <- prometheus.MustNewConstMetric(collector.serviceHealth.duration, prometheus.GaugeValue, duration, serviceName)
+		ch <- prometheus.MustNewConstMetric(collector.serviceHealth.responseSize, prometheus.GaugeValue, float64(responseSize), serviceName)
+		ch <- prometheus.MustNewConstMetric(collector.serviceHealth.statusCode, prometheus.GaugeValue, float64(statusCode), serviceName)
+		ch <- prometheus.MustNewConstMetric(collector.serviceHealth.failedMetrics, prometheus.GaugeValue, float64(failedMetrics[serviceName]), serviceName)
+	}
+}
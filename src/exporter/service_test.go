@@ -0,0 +1,103 @@
+package exporter
+
+import (
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+// serviceValue returns the value for the service health metric name with the label service="wallet"
+func serviceValue(require *require.Assertions, families map[string]*dto.MetricFamily, name string) float64 {
+	require.Contains(families, name)
+	require.Len(families[name].GetMetric(), 1)
+	m := families[name].GetMetric()[0]
+	require.Len(m.GetLabel(), 1)
+	require.Equal("service", m.GetLabel()[0].GetName())
+	require.Equal("wallet", m.GetLabel()[0].GetValue())
+	return m.GetGauge().GetValue()
+}
+
+func (suite *collectorSuit) TestHealthyServiceMetrics() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(float64(1), serviceValue(require, families, "skycoin_service_up"))
+	suite.Equal(float64(200), serviceValue(require, families, "skycoin_service_http_status_code"))
+	suite.Equal(float64(len(healthResponse)), serviceValue(require, families, "skycoin_service_response_size_bytes"))
+	suite.Equal(float64(0), serviceValue(require, families, "skycoin_service_failed_metrics"))
+	suite.True(serviceValue(require, families, "skycoin_service_scrape_duration_seconds") > 0)
+}
+
+func (suite *collectorSuit) TestUnhealthyServiceMetrics() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+
+	[[services.metrics]]
+		name = "missing"
+		url = "/api/v1/missing"
+		httpMethod = "GET"
+		path = "/value"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Value from a not existing endpoint"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(float64(0), serviceValue(require, families, "skycoin_service_up"))
+	suite.Equal(float64(404), serviceValue(require, families, "skycoin_service_http_status_code"))
+	suite.Equal(float64(1), serviceValue(require, families, "skycoin_service_failed_metrics"))
+}
+
+func (suite *collectorSuit) TestServiceWithErrorStatus() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/broken/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(float64(0), serviceValue(require, families, "skycoin_service_up"))
+	suite.Equal(float64(500), serviceValue(require, families, "skycoin_service_http_status_code"))
+	suite.Equal(float64(len(healthResponse)), serviceValue(require, families, "skycoin_service_response_size_bytes"))
+	suite.Equal(float64(1), serviceValue(require, families, "skycoin_service_failed_metrics"))
+	suite.NotContains(families, "skycoin_wallet_seq")
+}
@@ -0,0 +1,242 @@
+package exporter
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
+	"github.com/simelo/rextporter/src/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// sinkSample is a serie value ready to be sent to a sink, with the path built from the path template.
+type sinkSample struct {
+	path      string
+	value     float64
+	timestamp time.Time
+}
+
+// sink is a push based backend for the collected metrics, like Graphite or StatsD, sa sinkPusher.
+type sink interface {
+	// send the samples to the backend, the not finite values are skipped.
+	send(samples []sinkSample) error
+}
+
+// newSink returns the sink for the protocol in conf.
+func newSink(conf config.SinkOptions) (s sink, err error) {
+	switch conf.Type {
+	case config.KeySinkTypeGraphite:
+		return graphiteSink{address: conf.Address, timeout: conf.TimeoutOrDefault()}, nil
+	case config.KeySinkTypeStatsd:
+		return statsdSink{address: conf.Address, timeout: conf.TimeoutOrDefault()}, nil
+	}
+	return nil, fmt.Errorf("sink type %s is not supported", conf.Type)
+}
+
+// sinkPathSanitizer replace the characters who can not be in a path component, like the "." separator.
+var sinkPathSanitizer = regexp.MustCompile(`[^a-zA-Z0-9_\-]`)
+
+// sanitizeSinkPath returns component with the not allowed characters replaced by "_".
+func sanitizeSinkPath(component string) string {
+	return sinkPathSanitizer.ReplaceAllString(component, "_")
+}
+
+// sinkGroup is the metrics for a service and the names used to build their paths.
+type sinkGroup struct {
+	service  config.Service
+	metrics  []exposedMetric
+	gatherer prometheus.Gatherer
+}
+
+// sinkPath returns the namespace and metric name in the path for the serie name: the config name plus the serie
+// suffix(like "seq_up") for the service metrics, or the name without the namespace for the others(like "service_up").
+func (group sinkGroup) sinkPath(name, defaultNamespace string) config.SinkPath {
+	var found *exposedMetric
+	for idxMetric := range group.metrics {
+		metric := &group.metrics[idxMetric]
+		if name != metric.name && !strings.HasPrefix(name, metric.name+"_") {
+			continue
+		}
+		if found == nil || len(metric.name) > len(found.name) {
+			found = metric
+		}
+	}
+	if found == nil {
+		return config.SinkPath{
+			Namespace: defaultNamespace,
+			Service:   group.service.Name,
+			Metric:    strings.TrimPrefix(name, defaultNamespace+"_"),
+		}
+	}
+	return config.SinkPath{
+		Namespace: found.namespace,
+		Service:   group.service.Name,
+		Metric:    found.metric + strings.TrimPrefix(name, found.name),
+	}
+}
+
+// samples returns the series in families with their paths, the service name and location labels are skipped
+// because the service is already in the path, the other labels are added like ".<label name>.<label value>".
+func (group sinkGroup) samples(families []*dto.MetricFamily, pathTemplate *template.Template, defaultNamespace string, now time.Time) (samples []sinkSample, err error) {
+	skippedLabels := map[string]bool{config.KeyLabelService: true}
+	if group.service.HasServiceLabels() {
+		skippedLabels[config.KeyLabelLocation] = true
+	}
+	buf := new(bytes.Buffer)
+	for _, serie := range timeSeriesFromFamilies(families, now) {
+		path := group.sinkPath(serie.name, defaultNamespace)
+		path.Namespace = sanitizeSinkPath(path.Namespace)
+		path.Service = sanitizeSinkPath(path.Service)
+		path.Metric = sanitizeSinkPath(path.Metric)
+		buf.Reset()
+		if err = pathTemplate.Execute(buf, path); err != nil {
+			return nil, err
+		}
+		components := strings.Split(buf.String(), ".")
+		for _, label := range serie.labels {
+			if !skippedLabels[label.GetName()] {
+				components = append(components, sanitizeSinkPath(label.GetName()), sanitizeSinkPath(label.GetValue()))
+			}
+		}
+		nonEmpty := components[:0]
+		for _, component := range components {
+			if len(component) != 0 {
+				nonEmpty = append(nonEmpty, component)
+			}
+		}
+		samples = append(samples, sinkSample{
+			path:      strings.Join(nonEmpty, "."),
+			value:     serie.value,
+			timestamp: time.Unix(0, serie.timestampMs*int64(time.Millisecond)),
+		})
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].path < samples[j].path
+	})
+	return samples, nil
+}
+
+// sinkPusher collect the metrics for each service and send them to a sink each interval, sa config.SinkOptions.
+type sinkPusher struct {
+	conf             config.SinkOptions
+	name             string
+	sink             sink
+	pathTemplate     *template.Template
+	defaultNamespace string
+	groups           []sinkGroup
+	stop             chan struct{}
+	stopOnce         sync.Once
+	stopped          chan struct{}
+	started          bool
+}
+
+func newSinkPusher(conf config.SinkOptions, rootConf config.RootConfig, collector *SkycoinCollector) (p *sinkPusher, err error) {
+	const generalScopeErr = "error creating the sink pusher"
+	p = &sinkPusher{
+		conf:             conf,
+		name:             conf.Type + "://" + conf.Address,
+		defaultNamespace: rootConf.NamespaceOrDefault(),
+		stop:             make(chan struct{}),
+		stopped:          make(chan struct{}),
+	}
+	if p.sink, err = newSink(conf); err != nil {
+		return nil, util.ErrorFromThisScope(err.Error(), generalScopeErr)
+	}
+	if p.pathTemplate, err = conf.ParsePathTemplate(); err != nil {
+		errCause := fmt.Sprintln("can not parse the path template: ", err.Error())
+		return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	for _, serviceName := range collector.services {
+		srvConf, _ := rootConf.FindService(serviceName)
+		registry := prometheus.NewRegistry()
+		if err = registry.Register(scrapeCollector{collector: collector.forService(serviceName), timeout: conf.TimeoutOrDefault()}); err != nil {
+			errCause := fmt.Sprintln("can not register the collector for service ", serviceName, ": ", err.Error())
+			return nil, util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		p.groups = append(p.groups, sinkGroup{service: srvConf, metrics: serviceExposedMetrics(srvConf), gatherer: registry})
+	}
+	return p, nil
+}
+
+// newSinkPushersFromConfig returns a sink pusher for each sink in conf.
+func newSinkPushersFromConfig(conf config.RootConfig, collector *SkycoinCollector) (pushers []*sinkPusher, err error) {
+	for _, sinkConf := range conf.Sinks {
+		var p *sinkPusher
+		if p, err = newSinkPusher(sinkConf, conf, collector); err != nil {
+			return nil, err
+		}
+		pushers = append(pushers, p)
+	}
+	return pushers, nil
+}
+
+// push collect the metrics for all the services and send them to the sink.
+func (p *sinkPusher) push() (err error) {
+	const generalScopeErr = "error sending metrics to the sink"
+	var samples []sinkSample
+	now := time.Now()
+	for _, group := range p.groups {
+		var metricFamilies []*dto.MetricFamily
+		if metricFamilies, err = group.gatherer.Gather(); err != nil {
+			errCause := fmt.Sprintln("can not gather the metrics for service ", group.service.Name, ": ", err.Error())
+			return util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		var groupSamples []sinkSample
+		if groupSamples, err = group.samples(metricFamilies, p.pathTemplate, p.defaultNamespace, now); err != nil {
+			errCause := fmt.Sprintln("can not build the paths for service ", group.service.Name, ": ", err.Error())
+			return util.ErrorFromThisScope(errCause, generalScopeErr)
+		}
+		samples = append(samples, groupSamples...)
+	}
+	if err = p.sink.send(samples); err != nil {
+		selfmetrics.SinkSamples(p.name, "failed", len(samples))
+		errCause := fmt.Sprintln("can not send ", len(samples), " samples: ", err.Error())
+		return util.ErrorFromThisScope(errCause, generalScopeErr)
+	}
+	selfmetrics.SinkSamples(p.name, "sent", len(samples))
+	return nil
+}
+
+// start sending the metrics each interval in background until stopSinking is called.
+func (p *sinkPusher) start() {
+	log.WithFields(log.Fields{"sink": p.name, "interval": p.conf.IntervalOrDefault()}).Infoln("sending metrics to the sink in background")
+	p.started = true
+	go func() {
+		defer close(p.stopped)
+		push := func() {
+			if err := p.push(); err != nil {
+				log.WithError(err).WithField("sink", p.name).Errorln("can not send the metrics to the sink")
+			}
+		}
+		push()
+		ticker := time.NewTicker(p.conf.IntervalOrDefault())
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				push()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+}
+
+// stopSinking stop sending the metrics in background, it is safe to call it many times.
+func (p *sinkPusher) stopSinking() {
+	p.stopOnce.Do(func() {
+		close(p.stop)
+		if p.started {
+			<-p.stopped
+		}
+	})
+}
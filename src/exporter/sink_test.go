@@ -0,0 +1,187 @@
+package exporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+// sinkPusher returns a sink pusher for a wallet service with the seq metric, the sink options are added
+// to the sink section.
+func (suite *collectorSuit) sinkPusher(require *require.Assertions, sinkOptions string) *sinkPusher {
+	tomlConfig := fmt.Sprintf(`
+	[[sinks]]
+		%s
+`, sinkOptions) + suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Last block sequence"
+`)
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var pushers []*sinkPusher
+	pushers, err = newSinkPushersFromConfig(config.Config(), collector)
+	require.Nil(err)
+	require.Len(pushers, 1)
+	return pushers[0]
+}
+
+func (suite *collectorSuit) TestGraphiteSink() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(err)
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- ""
+			return
+		}
+		defer conn.Close()
+		content, _ := ioutil.ReadAll(conn)
+		received <- string(content)
+	}()
+	p := suite.sinkPusher(require, fmt.Sprintf(`type = "graphite"
+		address = "%s"`, listener.Addr().String()))
+
+	// NOTE(denisacostaq@gmail.com): When
+	err = p.push()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Nil(err)
+	var lines string
+	select {
+	case lines = <-received:
+	case <-time.After(5 * time.Second):
+		require.Fail("the graphite lines were not received")
+	}
+	suite.Contains(lines, "skycoin.wallet.seq 58894 1537581604\n")
+	suite.Contains(lines, "skycoin.wallet.seq_up 1 ")
+	suite.Contains(lines, "skycoin.wallet.service_up 1 ")
+}
+
+func (suite *collectorSuit) TestStatsdSink() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.Nil(err)
+	defer conn.Close()
+	p := suite.sinkPusher(require, fmt.Sprintf(`type = "statsd"
+		address = "%s"
+		pathTemplate = "legacy.{{.Service}}.{{.Metric}}"`, conn.LocalAddr().String()))
+
+	// NOTE(denisacostaq@gmail.com): When
+	err = p.push()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Nil(err)
+	require.Nil(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	packet := make([]byte, statsdMaxPacketSize)
+	var n int
+	n, _, err = conn.ReadFrom(packet)
+	require.Nil(err)
+	suite.Contains(string(packet[:n]), "legacy.wallet.seq:58894|g\n")
+	suite.Contains(string(packet[:n]), "legacy.wallet.seq_up:1|g\n")
+}
+
+func (suite *collectorSuit) TestSinkFailure() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	p := suite.sinkPusher(require, `type = "graphite"
+		address = "127.0.0.1:1"`)
+
+	// NOTE(denisacostaq@gmail.com): When
+	err := p.push()
+	p.stopSinking()
+	p.stopSinking()
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotNil(err)
+}
+
+func (suite *collectorSuit) TestStatsdPackets() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	samples := []sinkSample{
+		sinkSample{path: "skycoin.wallet.balance", value: -1.5},
+		sinkSample{path: "skycoin.wallet.nan", value: math.NaN()},
+	}
+	for idxSample := 0; idxSample < 100; idxSample++ {
+		samples = append(samples, sinkSample{path: fmt.Sprintf("skycoin.wallet.metric_%d", idxSample), value: 1})
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	packets := statsdPackets(samples)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(len(packets) > 1)
+	suite.True(strings.HasPrefix(string(packets[0]), "skycoin.wallet.balance:0|g\nskycoin.wallet.balance:-1.5|g\nskycoin.wallet.metric_0:1|g\n"))
+	lines := 0
+	for _, packet := range packets {
+		suite.True(len(packet) <= statsdMaxPacketSize)
+		suite.NotContains(string(packet), "nan")
+		lines += strings.Count(string(packet), "\n")
+	}
+	suite.Equal(102, lines)
+}
+
+func (suite *collectorSuit) TestSinkPathsWithLabels() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	serviceLabels := true
+	srvConf := config.Service{
+		Name:          "wallet",
+		Namespace:     "skycoin",
+		ServiceLabels: &serviceLabels,
+		Location:      config.Server{Location: "127.0.0.1"},
+		Metrics:       []config.Metric{config.Metric{Name: "seq"}, config.Metric{Name: "seq_time"}},
+	}
+	group := sinkGroup{service: srvConf, metrics: serviceExposedMetrics(srvConf)}
+	labels := []*dto.LabelPair{
+		&dto.LabelPair{Name: proto.String(config.KeyLabelService), Value: proto.String("wallet")},
+		&dto.LabelPair{Name: proto.String(config.KeyLabelLocation), Value: proto.String("127.0.0.1")},
+		&dto.LabelPair{Name: proto.String("address"), Value: proto.String("2.x.y")},
+	}
+	families := []*dto.MetricFamily{
+		&dto.MetricFamily{
+			Name:   proto.String("skycoin_seq_time"),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{&dto.Metric{Label: labels, Gauge: &dto.Gauge{Value: proto.Float64(2)}}},
+		},
+		&dto.MetricFamily{
+			Name:   proto.String("skycoin_seq_up"),
+			Type:   dto.MetricType_GAUGE.Enum(),
+			Metric: []*dto.Metric{&dto.Metric{Label: labels[:2], Gauge: &dto.Gauge{Value: proto.Float64(1)}}},
+		},
+	}
+	pathTemplate, err := config.SinkOptions{}.ParsePathTemplate()
+	require.Nil(err)
+
+	// NOTE(denisacostaq@gmail.com): When
+	var samples []sinkSample
+	samples, err = group.samples(families, pathTemplate, "skycoin", time.Now())
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Nil(err)
+	require.Len(samples, 2)
+	suite.Equal("skycoin.wallet.seq_time.address.2_x_y", samples[0].path)
+	suite.Equal(float64(2), samples[0].value)
+	suite.Equal("skycoin.wallet.seq_up", samples[1].path)
+}
@@ -0,0 +1,213 @@
+package exporter
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshotEndpointSuffix is added to the metrics handler endpoint to serve the metrics as a json snapshot,
+// like "/metrics.json".
+const snapshotEndpointSuffix = ".json"
+
+// jsonFloat is a float who can be encoded as json if it is not finite, like "NaN" or "+Inf".
+type jsonFloat float64
+
+// MarshalJSON encode the not finite values as strings.
+func (f jsonFloat) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		return json.Marshal(formatFloat(float64(f)))
+	}
+	return json.Marshal(float64(f))
+}
+
+// snapshotValue is a serie value for a metric in the snapshot, histograms and summaries have a value for
+// each _bucket(or quantile), _sum and _count serie.
+type snapshotValue struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Value     jsonFloat         `json:"value"`
+	Timestamp *time.Time        `json:"timestamp,omitempty"`
+}
+
+// snapshotMetric is a configured metric in the snapshot, with his status and current values.
+type snapshotMetric struct {
+	Service     string          `json:"service"`
+	Metric      string          `json:"metric"`
+	Name        string          `json:"name"`
+	Type        string          `json:"type"`
+	Up          bool            `json:"up"`
+	LastSuccess *time.Time      `json:"lastSuccess,omitempty"`
+	Values      []snapshotValue `json:"values"`
+}
+
+// snapshotService is a configured service in the snapshot with his status, sa the service health metrics.
+type snapshotService struct {
+	Name string `json:"name"`
+	Up   bool   `json:"up"`
+}
+
+// snapshot is the json document with the collected values for all the services.
+type snapshot struct {
+	Time     time.Time         `json:"time"`
+	Services []snapshotService `json:"services"`
+	Metrics  []snapshotMetric  `json:"metrics"`
+}
+
+// exposedMetric is a configured metric with the names and const labels used to find his series in the
+// gathered families, the const labels are needed because the same name can be used in many services.
+type exposedMetric struct {
+	service     string
+	metric      string
+	namespace   string
+	metricType  string
+	name        string
+	valuesName  string
+	constLabels prometheus.Labels
+}
+
+// serviceExposedMetrics returns the metrics in the service from srvConf.
+func serviceExposedMetrics(srvConf config.Service) (metrics []exposedMetric) {
+	for _, metricConf := range srvConf.Metrics {
+		metrics = append(metrics, exposedMetric{
+			service:     srvConf.Name,
+			metric:      metricConf.Name,
+			namespace:   srvConf.MetricNamespace(metricConf),
+			metricType:  metricConf.Options.Type,
+			name:        srvConf.MetricName(metricConf),
+			valuesName:  srvConf.ExposedMetricName(metricConf),
+			constLabels: srvConf.MetricConstLabels(),
+		})
+	}
+	return metrics
+}
+
+// exposedMetrics returns the metrics in all the services from conf.
+func exposedMetrics(conf config.RootConfig) (metrics []exposedMetric) {
+	for _, srvConf := range conf.Services {
+		metrics = append(metrics, serviceExposedMetrics(srvConf)...)
+	}
+	return metrics
+}
+
+// matches returns true if m has the metric const labels.
+func (metric exposedMetric) matches(m *dto.Metric) bool {
+	matched := 0
+	for _, label := range m.GetLabel() {
+		if value, ok := metric.constLabels[label.GetName()]; ok && value == label.GetValue() {
+			matched++
+		}
+	}
+	return matched == len(metric.constLabels)
+}
+
+// gaugeValue returns the value in the family name for the metric, ok is false if there is not one.
+func (metric exposedMetric) gaugeValue(families map[string]*dto.MetricFamily, name string) (val float64, ok bool) {
+	for _, m := range families[name].GetMetric() {
+		if metric.matches(m) {
+			return m.GetGauge().GetValue(), true
+		}
+	}
+	return 0, false
+}
+
+// snapshotMetric returns the status and values for the metric in families.
+func (metric exposedMetric) snapshotMetric(families map[string]*dto.MetricFamily, now time.Time) snapshotMetric {
+	snapshotMetric := snapshotMetric{
+		Service: metric.service,
+		Metric:  metric.metric,
+		Name:    metric.valuesName,
+		Type:    metric.metricType,
+		Values:  []snapshotValue{},
+	}
+	if up, ok := metric.gaugeValue(families, metric.name+"_up"); ok {
+		snapshotMetric.Up = up == 1
+	}
+	if lastSuccess, ok := metric.gaugeValue(families, metric.name+"_last_success_timestamp_seconds"); ok {
+		seconds, fraction := math.Modf(lastSuccess)
+		lastSuccessTime := time.Unix(int64(seconds), int64(fraction*1e9))
+		snapshotMetric.LastSuccess = &lastSuccessTime
+	}
+	mf, ok := families[metric.valuesName]
+	if !ok {
+		return snapshotMetric
+	}
+	matched := &dto.MetricFamily{Name: mf.Name, Type: mf.Type}
+	for _, m := range mf.GetMetric() {
+		if metric.matches(m) {
+			matched.Metric = append(matched.Metric, m)
+		}
+	}
+	for _, serie := range timeSeriesFromFamilies([]*dto.MetricFamily{matched}, now) {
+		value := snapshotValue{Name: serie.name, Value: jsonFloat(serie.value)}
+		for _, label := range serie.labels {
+			if _, isConst := metric.constLabels[label.GetName()]; isConst {
+				continue
+			}
+			if value.Labels == nil {
+				value.Labels = make(map[string]string)
+			}
+			value.Labels[label.GetName()] = label.GetValue()
+		}
+		if serie.timestamped {
+			timestamp := time.Unix(0, serie.timestampMs*int64(time.Millisecond))
+			value.Timestamp = &timestamp
+		}
+		snapshotMetric.Values = append(snapshotMetric.Values, value)
+	}
+	return snapshotMetric
+}
+
+// newSnapshot returns the snapshot for the metrics configured in conf from the gathered families.
+func newSnapshot(conf config.RootConfig, metricFamilies []*dto.MetricFamily, now time.Time) snapshot {
+	families := make(map[string]*dto.MetricFamily, len(metricFamilies))
+	for _, mf := range metricFamilies {
+		families[mf.GetName()] = mf
+	}
+	snap := snapshot{Time: now, Services: []snapshotService{}, Metrics: []snapshotMetric{}}
+	serviceUp := prometheus.BuildFQName(conf.NamespaceOrDefault(), "service", "up")
+	for _, serviceName := range serviceNames(conf) {
+		service := snapshotService{Name: serviceName}
+		for _, m := range families[serviceUp].GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == config.KeyLabelService && label.GetValue() == serviceName {
+					service.Up = m.GetGauge().GetValue() == 1
+				}
+			}
+		}
+		snap.Services = append(snap.Services, service)
+	}
+	for _, metric := range exposedMetrics(conf) {
+		snap.Metrics = append(snap.Metrics, metric.snapshotMetric(families, now))
+	}
+	return snap
+}
+
+// newSnapshotHandler serve the metrics from the collector as a json snapshot, with the service, name, type,
+// status, last success time and values for each configured metric in conf.
+func newSnapshotHandler(collector *SkycoinCollector, conf config.RootConfig) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		metricFamilies, err := gatherCollector(collector, scrapeTimeout(r))
+		if err != nil {
+			log.WithError(err).Errorln("can not gather the metrics")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		var content []byte
+		if content, err = json.MarshalIndent(newSnapshot(conf, metricFamilies, time.Now()), "", "  "); err != nil {
+			log.WithError(err).Errorln("can not encode the snapshot")
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err = w.Write(content); err != nil {
+			log.WithError(err).Errorln("can not write the response")
+		}
+	})
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+func (suite *collectorSuit) TestJSONFloat() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	values := []jsonFloat{jsonFloat(1.5), jsonFloat(math.NaN()), jsonFloat(math.Inf(1))}
+
+	// NOTE(denisacostaq@gmail.com): When
+	content, err := json.Marshal(values)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal(`[1.5,"NaN","+Inf"]`, string(content))
+}
+
+func (suite *collectorSuit) TestSnapshotHandler() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(suite.probeServiceConf()))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	recorder := httptest.NewRecorder()
+
+	// NOTE(denisacostaq@gmail.com): When
+	newSnapshotHandler(collector, config.Config()).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics.json", nil))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("application/json", recorder.Header().Get("Content-Type"))
+	var snap snapshot
+	require.Nil(json.Unmarshal(recorder.Body.Bytes(), &snap))
+	suite.Equal([]snapshotService{snapshotService{Name: "wallet", Up: true}, snapshotService{Name: "other_wallet", Up: false}}, snap.Services)
+	require.Len(snap.Metrics, 2)
+	wallet := snap.Metrics[0]
+	suite.Equal("wallet", wallet.Service)
+	suite.Equal("seq", wallet.Metric)
+	suite.Equal("skycoin_wallet_seq", wallet.Name)
+	suite.Equal(config.KeyTypeGauge, wallet.Type)
+	suite.True(wallet.Up)
+	require.NotNil(wallet.LastSuccess)
+	suite.True(time.Since(*wallet.LastSuccess) < time.Minute)
+	suite.Equal([]snapshotValue{snapshotValue{Name: "skycoin_wallet_seq", Value: 58894}}, wallet.Values)
+	otherWallet := snap.Metrics[1]
+	suite.Equal("other_wallet", otherWallet.Service)
+	suite.Equal("skycoin_other_wallet_seq", otherWallet.Name)
+	suite.False(otherWallet.Up)
+	suite.Nil(otherWallet.LastSuccess)
+	suite.Empty(otherWallet.Values)
+}
+
+func (suite *collectorSuit) TestSnapshotLabeledValues() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConfWithOptions(`
+		serviceLabels = true
+`, `
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+		timestampPath = "/blockchain/time"
+
+		[services.metrics.options]
+			type = "Counter"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+	require.Nil(config.NewConfigFromRawString(tomlConfig))
+	collector, err := newSkycoinCollector()
+	require.Nil(err)
+	var families []*dto.MetricFamily
+	families, err = gatherCollector(collector, time.Second)
+	require.Nil(err)
+
+	// NOTE(denisacostaq@gmail.com): When
+	snap := newSnapshot(config.Config(), families, time.Now())
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Len(snap.Metrics, 1)
+	suite.True(snap.Metrics[0].Up)
+	require.Len(snap.Metrics[0].Values, 1)
+	value := snap.Metrics[0].Values[0]
+	suite.Empty(value.Labels)
+	suite.Equal(jsonFloat(58894), value.Value)
+	require.NotNil(value.Timestamp)
+	suite.Equal(int64(1537581604), value.Timestamp.Unix())
+}
@@ -0,0 +1,178 @@
+package exporter
+
+import (
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/simelo/rextporter/src/config"
+	"github.com/simelo/rextporter/src/selfmetrics"
+)
+
+// metricState keep the last success update for a metric, to decide what to expose when it can not be
+// updated according to the metric stale policy. It is shared between the metric copies and safe for
+// concurrent use.
+type metricState struct {
+	mutex             sync.Mutex
+	stalePolicy       string
+	staleMaxAge       time.Duration
+	lastSuccessValues []sample
+	lastSuccess       time.Time
+	// service and metric are the names for the metric who own this state.
+	service string
+	metric  string
+	// accumulate, rawValues and totals are used by the counters who accumulate the upstream
+	// increases, sa config.CounterOptions. They are keyed by the serie label values.
+	accumulate      bool
+	lastAccumulated time.Time
+	rawValues       map[string]float64
+	totals          map[string]float64
+	// observed has when the data observed for last time was got, for the histograms and summaries. It is
+	// keyed by the serie label values.
+	observed map[string]time.Time
+}
+
+func newMetricState(metricConf config.Metric, srvConf config.Service) *metricState {
+	return &metricState{
+		stalePolicy: metricConf.Options.StalePolicyOrDefault(),
+		staleMaxAge: metricConf.Options.StaleMaxAge,
+		service:     srvConf.Name,
+		metric:      metricConf.Name,
+		accumulate:  metricConf.CounterOptions.Accumulate,
+		rawValues:   make(map[string]float64),
+		totals:      make(map[string]float64),
+		observed:    make(map[string]time.Time),
+	}
+}
+
+// labelsKeySeparator is used to join the label values in a serie key, it is not valid in utf-8 strings.
+const labelsKeySeparator = "\xff"
+
+// labelsKey identify a serie in a metric by his label values.
+func labelsKey(labelValues []string) string {
+	return strings.Join(labelValues, labelsKeySeparator)
+}
+
+// observeOnce returns true if the values for the serie with labelValues in the data got at the 'at' time
+// should be observed, and record them as observed. The same data(for example from a background poll) is
+// observed only once no matter how many times it is collected.
+func (state *metricState) observeOnce(labelValues []string, at time.Time) bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	key := labelsKey(labelValues)
+	if !at.After(state.observed[key]) {
+		return false
+	}
+	state.observed[key] = at
+	return true
+}
+
+// dropUnobserved returns the label values for the series observed before who are not in observations,
+// from the data got at the 'at' time, and forget them so they can be deleted from the histogram or summary
+// vector. Nothing is dropped for data older than the last success one.
+func (state *metricState) dropUnobserved(observations []observation, at time.Time) (dropped [][]string) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if at.Before(state.lastSuccess) {
+		return nil
+	}
+	current := make(map[string]bool, len(observations))
+	for _, o := range observations {
+		current[labelsKey(o.labelValues)] = true
+	}
+	for key := range state.observed {
+		if !current[key] {
+			delete(state.observed, key)
+			dropped = append(dropped, strings.Split(key, labelsKeySeparator))
+		}
+	}
+	return dropped
+}
+
+// accumulated returns the accumulated totals for the upstream values in samples, got at the 'at' time. The
+// increases are added to the totals and a decrease is treated as a reset, so the upstream value is added as
+// is. Values older than the last accumulated ones are not added, the current totals are returned for them.
+func (state *metricState) accumulated(samples []sample, at time.Time) (totals []sample) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	outdated := at.Before(state.lastAccumulated)
+	totals = make([]sample, len(samples))
+	for idxSample, s := range samples {
+		key := labelsKey(s.labelValues)
+		if !outdated {
+			rawValue, ok := state.rawValues[key]
+			switch {
+			case !ok:
+				state.totals[key] += s.val
+			case s.val >= rawValue:
+				state.totals[key] += s.val - rawValue
+			default:
+				state.totals[key] += s.val
+				selfmetrics.CounterReset(state.service, state.metric)
+			}
+			state.rawValues[key] = s.val
+		}
+		totals[idxSample] = sample{labelValues: s.labelValues, val: state.totals[key], timestamp: s.timestamp}
+	}
+	if !outdated {
+		state.lastAccumulated = at
+	}
+	return totals
+}
+
+// succeeded records samples as the last success values, got at the 'at' time. Values older than the
+// ones already recorded are ignored, it can happen if concurrent scrapes finish out of order.
+func (state *metricState) succeeded(samples []sample, at time.Time) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if at.Before(state.lastSuccess) {
+		return
+	}
+	state.lastSuccessValues = samples
+	state.lastSuccess = at
+}
+
+// lastSuccessTime returns when the metric was success updated for last time, ok is false if never.
+func (state *metricState) lastSuccessTime() (lastSuccess time.Time, ok bool) {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.lastSuccess, !state.lastSuccess.IsZero()
+}
+
+// keepStale returns true if a metric who fail should keep being exposed with the previous values.
+func (state *metricState) keepStale(now time.Time) bool {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.keepStaleUnlocked(now)
+}
+
+func (state *metricState) keepStaleUnlocked(now time.Time) bool {
+	if state.stalePolicy != config.KeyStalePolicyKeep || state.lastSuccess.IsZero() {
+		return false
+	}
+	return state.staleMaxAge == 0 || now.Sub(state.lastSuccess) <= state.staleMaxAge
+}
+
+// staleSamples returns the samples to be exposed for a metric who fail according to his stale policy,
+// isVec says if the metric has labels, it is used to expose a NaN before a first success update.
+func (state *metricState) staleSamples(now time.Time, isVec bool) []sample {
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	switch state.stalePolicy {
+	case config.KeyStalePolicyNaN:
+		if len(state.lastSuccessValues) == 0 && !isVec {
+			return []sample{sample{val: math.NaN()}}
+		}
+		samples := make([]sample, len(state.lastSuccessValues))
+		for idxSample, s := range state.lastSuccessValues {
+			samples[idxSample] = sample{labelValues: s.labelValues, val: math.NaN()}
+		}
+		return samples
+	case config.KeyStalePolicyKeep:
+		if state.keepStaleUnlocked(now) {
+			return state.lastSuccessValues
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package exporter
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/simelo/rextporter/src/config"
+	"github.com/stretchr/testify/require"
+)
+
+// failingGaugeConf returns a gauge who can not be updated because his path does not exist, with
+// stalePolicy as metric options.
+func (suite *collectorSuit) failingGaugeConf(stalePolicy string) string {
+	return suite.serviceConf(`
+	[[services.metrics]]
+		name = "missing"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/missing"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Not existing value"
+` + stalePolicy)
+}
+
+func (suite *collectorSuit) TestStaleDropPolicy() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.failingGaugeConf(`
+			stalePolicy = "drop"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.NotContains(families, "skycoin_wallet_missing")
+	suite.NotContains(families, "skycoin_wallet_missing_last_success_timestamp_seconds")
+	require.Contains(families, "skycoin_wallet_missing_up")
+	suite.Equal(float64(0), families["skycoin_wallet_missing_up"].GetMetric()[0].GetGauge().GetValue())
+}
+
+func (suite *collectorSuit) TestStaleNaNPolicy() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.failingGaugeConf(`
+			stalePolicy = "nan"
+`)
+	require := require.New(suite.T())
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_missing")
+	require.Len(families["skycoin_wallet_missing"].GetMetric(), 1)
+	suite.True(math.IsNaN(families["skycoin_wallet_missing"].GetMetric()[0].GetGauge().GetValue()))
+}
+
+func (suite *collectorSuit) TestLastSuccessTimestamp() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	tomlConfig := suite.serviceConf(`
+	[[services.metrics]]
+		name = "seq"
+		url = "/api/v1/health"
+		httpMethod = "GET"
+		path = "/blockchain/head/seq"
+
+		[services.metrics.options]
+			type = "Gauge"
+			description = "Head sequence"
+`)
+	require := require.New(suite.T())
+	before := time.Now()
+
+	// NOTE(denisacostaq@gmail.com): When
+	families := gather(require, tomlConfig)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	require.Contains(families, "skycoin_wallet_seq_last_success_timestamp_seconds")
+	lastSuccess := families["skycoin_wallet_seq_last_success_timestamp_seconds"].GetMetric()[0].GetGauge().GetValue()
+	suite.True(lastSuccess >= float64(before.Unix()))
+	suite.True(lastSuccess <= float64(time.Now().Unix()+1))
+}
+
+func (suite *collectorSuit) TestStaleKeepPolicyMaxAge() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	state := newMetricState(config.Metric{Options: config.MetricOptions{StaleMaxAge: time.Minute}}, config.Service{})
+	lastSuccess := time.Now()
+	state.succeeded([]sample{sample{val: 1}}, lastSuccess)
+
+	// NOTE(denisacostaq@gmail.com): When
+	fresh := state.staleSamples(lastSuccess.Add(time.Second), false)
+	stale := state.staleSamples(lastSuccess.Add(2*time.Minute), false)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal([]sample{sample{val: 1}}, fresh)
+	suite.Len(stale, 0)
+	suite.True(state.keepStale(lastSuccess.Add(time.Second)))
+	suite.False(state.keepStale(lastSuccess.Add(2 * time.Minute)))
+}
+
+func (suite *collectorSuit) TestAccumulatedCounter() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	metricConf := config.Metric{
+		Name:           "requests",
+		Options:        config.MetricOptions{Type: config.KeyTypeCounter},
+		CounterOptions: config.CounterOptions{Accumulate: true},
+	}
+	state := newMetricState(metricConf, config.Service{Name: "accumulated"})
+	require := require.New(suite.T())
+	resetsBefore := selfCounter(require, "rextporter_counter_resets_total", "accumulated", "requests")
+	start := time.Now()
+	upstream := func(vals ...float64) (samples []sample) {
+		for idxVal, val := range vals {
+			samples = append(samples, sample{labelValues: []string{strconv.Itoa(idxVal)}, val: val})
+		}
+		return samples
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	first := state.accumulated(upstream(10, 1), start)
+	increased := state.accumulated(upstream(15, 2), start.Add(time.Second))
+	restarted := state.accumulated(upstream(3, 4), start.Add(2*time.Second))
+	outdated := state.accumulated(upstream(100, 100), start.Add(time.Second))
+	last := state.accumulated(upstream(5, 4), start.Add(3*time.Second))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Equal(upstream(10, 1), first)
+	suite.Equal(upstream(15, 2), increased)
+	suite.Equal(upstream(18, 4), restarted)
+	suite.Equal(upstream(18, 4), outdated)
+	suite.Equal(upstream(20, 4), last)
+	suite.Equal(resetsBefore+1, selfCounter(require, "rextporter_counter_resets_total", "accumulated", "requests"))
+}
+
+func (suite *collectorSuit) TestDropUnobservedSeries() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	metricConf := config.Metric{Name: "peer_height", Options: config.MetricOptions{Type: config.KeyTypeHistogram}}
+	state := newMetricState(metricConf, config.Service{Name: "wallet"})
+	start := time.Now()
+	peers := func(ids ...string) (observations []observation) {
+		for _, id := range ids {
+			observations = append(observations, observation{labelValues: []string{id}, values: []float64{180}})
+		}
+		return observations
+	}
+	observe := func(observations []observation, at time.Time) [][]string {
+		for _, o := range observations {
+			state.observeOnce(o.labelValues, at)
+		}
+		dropped := state.dropUnobserved(observations, at)
+		state.succeeded(nil, at)
+		return dropped
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	first := observe(peers("1", "2"), start)
+	disconnected := observe(peers("1"), start.Add(time.Second))
+	outdated := state.dropUnobserved(peers(), start)
+	reconnected := state.observeOnce([]string{"2"}, start.Add(2*time.Second))
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Len(first, 0)
+	suite.Equal([][]string{[]string{"2"}}, disconnected)
+	suite.Len(outdated, 0)
+	suite.True(reconnected)
+}
@@ -0,0 +1,59 @@
+package exporter
+
+import (
+	"bytes"
+	"math"
+	"net"
+	"time"
+)
+
+// statsdMaxPacketSize is the max size for a StatsD packet, so it fit in a single ethernet frame.
+const statsdMaxPacketSize = 1432
+
+// statsdSink send the samples to StatsD as gauges over UDP, the values are the current ones(for the counters
+// too) so StatsD does not aggregate them.
+type statsdSink struct {
+	address string
+	timeout time.Duration
+}
+
+// statsdPackets returns samples as StatsD gauges like "<path>:<value>|g", with many lines in each packet. A
+// negative value is sent after a 0 one because StatsD take "-<value>" as a decrement of the gauge.
+func statsdPackets(samples []sinkSample) (packets [][]byte) {
+	packet := new(bytes.Buffer)
+	for _, sample := range samples {
+		if math.IsNaN(sample.value) || math.IsInf(sample.value, 0) {
+			continue
+		}
+		lines := sample.path + ":" + formatFloat(sample.value) + "|g\n"
+		if sample.value < 0 {
+			lines = sample.path + ":0|g\n" + lines
+		}
+		if packet.Len() != 0 && packet.Len()+len(lines) > statsdMaxPacketSize {
+			packets = append(packets, append([]byte(nil), packet.Bytes()...))
+			packet.Reset()
+		}
+		packet.WriteString(lines)
+	}
+	if packet.Len() != 0 {
+		packets = append(packets, packet.Bytes())
+	}
+	return packets
+}
+
+func (s statsdSink) send(samples []sinkSample) (err error) {
+	var conn net.Conn
+	if conn, err = net.DialTimeout("udp", s.address, s.timeout); err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	for _, packet := range statsdPackets(samples) {
+		if _, err = conn.Write(packet); err != nil {
+			return err
+		}
+	}
+	return nil
+}
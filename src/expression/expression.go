@@ -0,0 +1,368 @@
+// Package expression parse and evaluate the arithmetic expressions used by the derived metrics, like
+// "unconfirmed / open_connections" or "sum(wallet*.open_connections)".
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	// AggregationSum is the sum of all the values.
+	AggregationSum = "sum"
+	// AggregationAvg is the average of all the values.
+	AggregationAvg = "avg"
+	// AggregationMin is the lowest of all the values.
+	AggregationMin = "min"
+	// AggregationMax is the greatest of all the values.
+	AggregationMax = "max"
+	// AggregationCount is how many values there are.
+	AggregationCount = "count"
+)
+
+// Ref is a metric referred in an expression like "metric" or "service.metric", Service is empty for
+// the metrics in the same service than the expression. If the metric is referred inside an aggregation
+// like "sum(wallet*.metric)" Aggregated is true and Service can be a pattern, sa path.Match.
+type Ref struct {
+	Service    string
+	Metric     string
+	Aggregated bool
+}
+
+func (ref Ref) String() string {
+	if len(ref.Service) == 0 {
+		return ref.Metric
+	}
+	return ref.Service + "." + ref.Metric
+}
+
+// Values returns the values for a metric referred in an expression, a referred metric out of an
+// aggregation should have a single value.
+type Values func(ref Ref) ([]float64, error)
+
+// Expression is an arithmetic expression with numbers, metric references, aggregations,
+// parenthesis and the +, -, *, / operators, sa Parse.
+type Expression struct {
+	str  string
+	root node
+	refs []Ref
+}
+
+// Parse returns the expression in str or an error if it is not well formed.
+func Parse(str string) (expr *Expression, err error) {
+	p := &parser{str: str, seen: make(map[Ref]bool)}
+	var root node
+	if root, err = p.parseSum(); err != nil {
+		return nil, err
+	}
+	p.skipSpaces()
+	if p.pos < len(p.str) {
+		return nil, p.errorf("unexpected %q", p.str[p.pos])
+	}
+	return &Expression{str: str, root: root, refs: p.refs}, nil
+}
+
+// Refs returns the metrics referred in the expression, without duplicates.
+func (expr *Expression) Refs() []Ref {
+	return expr.refs
+}
+
+func (expr *Expression) String() string {
+	return expr.str
+}
+
+// Eval returns the expression value using values to get the referred metrics values.
+func (expr *Expression) Eval(values Values) (float64, error) {
+	return expr.root.eval(values)
+}
+
+type node interface {
+	eval(values Values) (float64, error)
+}
+
+type number float64
+
+func (n number) eval(values Values) (float64, error) {
+	return float64(n), nil
+}
+
+type refNode Ref
+
+func (n refNode) eval(values Values) (float64, error) {
+	vals, err := values(Ref(n))
+	if err != nil {
+		return 0, err
+	}
+	if len(vals) != 1 {
+		return 0, fmt.Errorf("metric %s should have a single value, it has %d", Ref(n), len(vals))
+	}
+	return vals[0], nil
+}
+
+type aggregation struct {
+	function string
+	ref      Ref
+}
+
+func (n aggregation) eval(values Values) (val float64, err error) {
+	var vals []float64
+	if vals, err = values(n.ref); err != nil {
+		return 0, err
+	}
+	switch n.function {
+	case AggregationCount:
+		return float64(len(vals)), nil
+	case AggregationSum:
+		for _, v := range vals {
+			val += v
+		}
+		return val, nil
+	}
+	if len(vals) == 0 {
+		return 0, fmt.Errorf("there are not values for %s(%s)", n.function, n.ref)
+	}
+	switch n.function {
+	case AggregationAvg:
+		for _, v := range vals {
+			val += v
+		}
+		return val / float64(len(vals)), nil
+	case AggregationMin:
+		val = math.Inf(1)
+		for _, v := range vals {
+			val = math.Min(val, v)
+		}
+		return val, nil
+	case AggregationMax:
+		val = math.Inf(-1)
+		for _, v := range vals {
+			val = math.Max(val, v)
+		}
+		return val, nil
+	}
+	return 0, fmt.Errorf("unknown aggregation %s", n.function)
+}
+
+type negation struct {
+	operand node
+}
+
+func (n negation) eval(values Values) (float64, error) {
+	val, err := n.operand.eval(values)
+	return -val, err
+}
+
+type operation struct {
+	operator    byte
+	left, right node
+}
+
+func (n operation) eval(values Values) (val float64, err error) {
+	var left, right float64
+	if left, err = n.left.eval(values); err != nil {
+		return 0, err
+	}
+	if right, err = n.right.eval(values); err != nil {
+		return 0, err
+	}
+	switch n.operator {
+	case '+':
+		return left + right, nil
+	case '-':
+		return left - right, nil
+	case '*':
+		return left * right, nil
+	case '/':
+		if right == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return left / right, nil
+	}
+	return 0, fmt.Errorf("unknown operator %q", n.operator)
+}
+
+// parser is a recursive descent parser for the grammar:
+//
+//	sum     = product {("+" | "-") product}
+//	product = unary {("*" | "/") unary}
+//	unary   = "-" unary | primary
+//	primary = number | "(" sum ")" | function "(" ref ")" | ref
+//	ref     = [service "."] metric
+type parser struct {
+	str  string
+	pos  int
+	refs []Ref
+	seen map[Ref]bool
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid expression %q at %d: %s", p.str, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.str) && (p.str[p.pos] == ' ' || p.str[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// peek returns the next not space character, or 0 at the end.
+func (p *parser) peek() byte {
+	p.skipSpaces()
+	if p.pos < len(p.str) {
+		return p.str[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) parseSum() (n node, err error) {
+	if n, err = p.parseProduct(); err != nil {
+		return nil, err
+	}
+	for operator := p.peek(); operator == '+' || operator == '-'; operator = p.peek() {
+		p.pos++
+		var right node
+		if right, err = p.parseProduct(); err != nil {
+			return nil, err
+		}
+		n = operation{operator: operator, left: n, right: right}
+	}
+	return n, nil
+}
+
+func (p *parser) parseProduct() (n node, err error) {
+	if n, err = p.parseUnary(); err != nil {
+		return nil, err
+	}
+	for operator := p.peek(); operator == '*' || operator == '/'; operator = p.peek() {
+		p.pos++
+		var right node
+		if right, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		n = operation{operator: operator, left: n, right: right}
+	}
+	return n, nil
+}
+
+func (p *parser) parseUnary() (n node, err error) {
+	if p.peek() == '-' {
+		p.pos++
+		if n, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		return negation{operand: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (n node, err error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, p.errorf("unexpected end")
+	case c == '(':
+		p.pos++
+		if n, err = p.parseSum(); err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return n, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	}
+	start := p.pos
+	name := p.identifier(false)
+	if len(name) == 0 {
+		return nil, p.errorf("unexpected %q", c)
+	}
+	if p.peek() == '(' {
+		switch name {
+		case AggregationSum, AggregationAvg, AggregationMin, AggregationMax, AggregationCount:
+		default:
+			return nil, p.errorf("unknown function %s", name)
+		}
+		p.pos++
+		p.skipSpaces()
+		var ref Ref
+		if ref, err = p.parseRef(true); err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return aggregation{function: name, ref: p.addRef(ref)}, nil
+	}
+	p.pos = start
+	var ref Ref
+	if ref, err = p.parseRef(false); err != nil {
+		return nil, err
+	}
+	return refNode(p.addRef(ref)), nil
+}
+
+func (p *parser) parseNumber() (n node, err error) {
+	start := p.pos
+	for p.pos < len(p.str) && (p.str[p.pos] == '.' || (p.str[p.pos] >= '0' && p.str[p.pos] <= '9')) {
+		p.pos++
+	}
+	if p.pos < len(p.str) && (p.str[p.pos] == 'e' || p.str[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.str) && (p.str[p.pos] == '+' || p.str[p.pos] == '-') {
+			p.pos++
+		}
+		for p.pos < len(p.str) && p.str[p.pos] >= '0' && p.str[p.pos] <= '9' {
+			p.pos++
+		}
+	}
+	var val float64
+	if val, err = strconv.ParseFloat(p.str[start:p.pos], 64); err != nil {
+		return nil, p.errorf("invalid number %s", p.str[start:p.pos])
+	}
+	return number(val), nil
+}
+
+// parseRef parse a metric reference, the service can be a pattern inside an aggregation.
+func (p *parser) parseRef(aggregated bool) (ref Ref, err error) {
+	ref.Aggregated = aggregated
+	first := p.identifier(aggregated)
+	if p.pos < len(p.str) && p.str[p.pos] == '.' {
+		p.pos++
+		ref.Service = first
+		ref.Metric = p.identifier(false)
+	} else {
+		ref.Metric = first
+	}
+	if len(ref.Metric) == 0 || strings.ContainsAny(ref.Metric, "*?") {
+		return ref, p.errorf("invalid metric reference")
+	}
+	return ref, nil
+}
+
+// identifier returns the metric or service name at the current position, with the '*' and '?'
+// pattern characters if pattern is true.
+func (p *parser) identifier(pattern bool) string {
+	start := p.pos
+	for ; p.pos < len(p.str); p.pos++ {
+		c := p.str[p.pos]
+		isNameChar := c == '_' || c == ':' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isNameChar && !(pattern && (c == '*' || c == '?')) {
+			break
+		}
+	}
+	return p.str[start:p.pos]
+}
+
+func (p *parser) addRef(ref Ref) Ref {
+	if !p.seen[ref] {
+		p.seen[ref] = true
+		p.refs = append(p.refs, ref)
+	}
+	return ref
+}
@@ -0,0 +1,145 @@
+package expression
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type expressionSuit struct {
+	suite.Suite
+	values map[Ref][]float64
+}
+
+func (suite *expressionSuit) SetupTest() {
+	suite.values = map[Ref][]float64{
+		Ref{Metric: "unconfirmed"}:                                            []float64{6},
+		Ref{Metric: "open_connections"}:                                       []float64{3},
+		Ref{Service: "wallet1", Metric: "open_connections"}:                   []float64{4},
+		Ref{Service: "wallet*", Metric: "open_connections", Aggregated: true}: []float64{3, 4, 5},
+		Ref{Metric: "open_connections", Aggregated: true}:                     []float64{3},
+		Ref{Service: "empty", Metric: "open_connections", Aggregated: true}:   []float64{},
+		Ref{Service: "wallet2", Metric: "open_connections_by_peer"}:           []float64{1, 2},
+		Ref{Service: "wallet2", Metric: "unconfirmed"}:                        []float64{0},
+		Ref{Service: "wallet3", Metric: "open_connections"}:                   nil,
+		Ref{Service: "wallet?", Metric: "unconfirmed", Aggregated: true}:      []float64{6, 0},
+	}
+}
+
+func TestExpressionSuit(t *testing.T) {
+	suite.Run(t, new(expressionSuit))
+}
+
+func (suite *expressionSuit) eval(str string) (float64, error) {
+	expr, err := Parse(str)
+	suite.Require().Nil(err)
+	return expr.Eval(func(ref Ref) ([]float64, error) {
+		vals, ok := suite.values[ref]
+		if !ok {
+			return nil, errors.New("unknown metric " + ref.String())
+		}
+		return vals, nil
+	})
+}
+
+func (suite *expressionSuit) TestArithmetic() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	str := "-(1 + 2 * 3 - 4) / 2 + 1.5e1"
+
+	// NOTE(denisacostaq@gmail.com): When
+	val, err := suite.eval(str)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal(13.5, val)
+}
+
+func (suite *expressionSuit) TestRefs() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	str := "unconfirmed / open_connections + wallet1.open_connections - sum(wallet*.open_connections) + unconfirmed"
+
+	// NOTE(denisacostaq@gmail.com): When
+	expr, err := Parse(str)
+	val, evalErr := suite.eval(str)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(err)
+	suite.Equal(
+		[]Ref{
+			Ref{Metric: "unconfirmed"},
+			Ref{Metric: "open_connections"},
+			Ref{Service: "wallet1", Metric: "open_connections"},
+			Ref{Service: "wallet*", Metric: "open_connections", Aggregated: true},
+		},
+		expr.Refs())
+	suite.Equal(str, expr.String())
+	suite.Nil(evalErr)
+	suite.Equal(float64(6/3+4-12+6), val)
+}
+
+func (suite *expressionSuit) TestAggregations() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	expressions := map[string]float64{
+		"sum(wallet*.open_connections)":   12,
+		"avg(wallet*.open_connections)":   4,
+		"min(wallet*.open_connections)":   3,
+		"max(wallet*.open_connections)":   5,
+		"count(wallet*.open_connections)": 3,
+		"sum( open_connections )":         3,
+		"sum(empty.open_connections)":     0,
+		"max(wallet?.unconfirmed)":        6,
+	}
+
+	for str, expected := range expressions {
+		// NOTE(denisacostaq@gmail.com): When
+		val, err := suite.eval(str)
+
+		// NOTE(denisacostaq@gmail.com): Assert
+		suite.Nil(err, str)
+		suite.Equal(expected, val, str)
+	}
+}
+
+func (suite *expressionSuit) TestEvalErrors() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	expressions := []string{
+		"unconfirmed / wallet2.unconfirmed",
+		"wallet2.open_connections_by_peer",
+		"wallet3.open_connections",
+		"avg(empty.open_connections)",
+		"not_defined",
+	}
+
+	for _, str := range expressions {
+		// NOTE(denisacostaq@gmail.com): When
+		_, err := suite.eval(str)
+
+		// NOTE(denisacostaq@gmail.com): Assert
+		suite.NotNil(err, str)
+	}
+}
+
+func (suite *expressionSuit) TestParseErrors() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	expressions := []string{
+		"",
+		"1 +",
+		"(1 + 2",
+		"1 2",
+		"median(open_connections)",
+		"sum(wallet*.open_*)",
+		"wallet*.open_connections",
+		"sum(open_connections",
+		"1..2",
+		"open_connections.",
+	}
+
+	for _, str := range expressions {
+		// NOTE(denisacostaq@gmail.com): When
+		_, err := Parse(str)
+
+		// NOTE(denisacostaq@gmail.com): Assert
+		suite.NotNil(err, str)
+	}
+}
@@ -0,0 +1,9 @@
+// Package otlppb has the OpenTelemetry protocol(OTLP) metrics messages, the *.proto files are the ones in
+// opentelemetry-proto v1.0.0(https://github.com/open-telemetry/opentelemetry-proto) without the comments, and
+// the *.pb.go files are generated from them with protoc-gen-gogofast(github.com/gogo/protobuf), for example:
+//
+//	protoc -I. -I$GOPATH/src/github.com/gogo/protobuf --gogofast_out=paths=source_relative,Mopentelemetry/proto/common/v1/common.proto=github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/common/v1,Mopentelemetry/proto/resource/v1/resource.proto=github.com/simelo/rextporter/src/otlppb/opentelemetry/proto/resource/v1:. opentelemetry/proto/metrics/v1/metrics.proto
+//
+// The proto3 optional fields are declared as a oneof with a single field(like sum_ { double sum = 5; }),
+// because protoc-gen-gogo does not support them, the encoding is the same.
+package otlppb
@@ -0,0 +1,5 @@
+// Package prompb has the messages of the Prometheus remote write protocol, the *.pb.go files are generated
+// from the *.proto ones with protoc-gen-gogofast(github.com/gogo/protobuf), for example:
+//
+//	protoc -I. -I$GOPATH/src/github.com/gogo/protobuf --gogofast_out=. types.proto remote.proto
+package prompb
@@ -0,0 +1,204 @@
+// Package relabel apply Prometheus style relabel rules(like the relabel_configs) to the label sets of the
+// exposed metrics, the metric name is in the "__name__" label so it can be renamed, kept or dropped too.
+package relabel
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/common/model"
+)
+
+const (
+	// ActionReplace set the target label to the replacement if the regex match the source labels value.
+	ActionReplace = "replace"
+	// ActionKeep drop the metric if the regex does not match the source labels value.
+	ActionKeep = "keep"
+	// ActionDrop drop the metric if the regex match the source labels value.
+	ActionDrop = "drop"
+	// ActionLabelDrop remove the labels whose name match the regex.
+	ActionLabelDrop = "labeldrop"
+	// ActionLabelMap copy the labels whose name match the regex to the label named like the replacement.
+	ActionLabelMap = "labelmap"
+	// ActionHashMod set the target label to the modulus of a hash of the source labels value.
+	ActionHashMod = "hashmod"
+
+	defaultSeparator   = ";"
+	defaultRegex       = "(.*)"
+	defaultReplacement = "$1"
+)
+
+// Config is a relabel rule with the same meaning than in the Prometheus relabel_configs, the not defined
+// fields get the Prometheus defaults: the "replace" action, the ";" separator, the "(.*)" regex and the
+// "$1" replacement.
+type Config struct {
+	// SourceLabels are the labels whose values are joined with the separator to be matched by the regex.
+	SourceLabels []string `json:"sourceLabels"`
+	Separator    string   `json:"separator"`
+
+	// Regex is anchored at both ends, like in Prometheus.
+	Regex string `json:"regex"`
+
+	// Modulus is the modulus for the hashmod action.
+	Modulus uint64 `json:"modulus"`
+
+	// TargetLabel is the label set by the replace and hashmod actions, it can refer the regex groups.
+	TargetLabel string `json:"targetLabel"`
+
+	// Replacement can refer the regex groups like "$1", an empty replacement remove the target label.
+	Replacement *string `json:"replacement"`
+
+	Action string `json:"action"`
+}
+
+// Rule is a relabel rule ready to be applied, sa Compile.
+type Rule struct {
+	sourceLabels []string
+	separator    string
+	regex        *regexp.Regexp
+	modulus      uint64
+	targetLabel  string
+	replacement  string
+	action       string
+}
+
+// Compile returns the rule for conf or an error if it is not valid.
+func Compile(conf Config) (rule Rule, err error) {
+	rule = Rule{
+		sourceLabels: conf.SourceLabels,
+		separator:    conf.Separator,
+		modulus:      conf.Modulus,
+		targetLabel:  conf.TargetLabel,
+		replacement:  defaultReplacement,
+		action:       strings.ToLower(conf.Action),
+	}
+	if len(rule.separator) == 0 {
+		rule.separator = defaultSeparator
+	}
+	if conf.Replacement != nil {
+		rule.replacement = *conf.Replacement
+	}
+	if len(rule.action) == 0 {
+		rule.action = ActionReplace
+	}
+	regex := conf.Regex
+	if len(regex) == 0 {
+		regex = defaultRegex
+	}
+	if rule.regex, err = regexp.Compile("^(?:" + regex + ")$"); err != nil {
+		return rule, fmt.Errorf("relabel regex %s is not valid: %s", regex, err.Error())
+	}
+	switch rule.action {
+	case ActionReplace:
+		if len(rule.targetLabel) == 0 {
+			return rule, errors.New("the relabel replace action requires a target label")
+		}
+		if !strings.Contains(rule.targetLabel, "$") && !model.LabelName(rule.targetLabel).IsValid() {
+			return rule, fmt.Errorf("relabel target label %s is not a valid label name", rule.targetLabel)
+		}
+	case ActionHashMod:
+		if !model.LabelName(rule.targetLabel).IsValid() {
+			return rule, fmt.Errorf("relabel target label %s is not a valid label name", rule.targetLabel)
+		}
+		if rule.modulus == 0 {
+			return rule, errors.New("the relabel hashmod action requires a modulus")
+		}
+	case ActionKeep, ActionDrop, ActionLabelDrop, ActionLabelMap:
+	default:
+		return rule, fmt.Errorf("relabel action %s is not valid", conf.Action)
+	}
+	return rule, nil
+}
+
+// CompileAll returns the rules for confs in the same order, or the first error found.
+func CompileAll(confs []Config) (rules []Rule, err error) {
+	for _, conf := range confs {
+		var rule Rule
+		if rule, err = Compile(conf); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// sum64 returns the last 8 bytes of hash as a number, like Prometheus does for the hashmod action.
+func sum64(hash [md5.Size]byte) (sum uint64) {
+	for _, b := range hash[md5.Size-8:] {
+		sum = sum<<8 | uint64(b)
+	}
+	return sum
+}
+
+// apply the rule to labels, it returns false if the metric should be dropped.
+func (rule Rule) apply(labels map[string]string) bool {
+	values := make([]string, len(rule.sourceLabels))
+	for idxLabel, name := range rule.sourceLabels {
+		values[idxLabel] = labels[name]
+	}
+	val := strings.Join(values, rule.separator)
+	switch rule.action {
+	case ActionKeep:
+		return rule.regex.MatchString(val)
+	case ActionDrop:
+		return !rule.regex.MatchString(val)
+	case ActionReplace:
+		indexes := rule.regex.FindStringSubmatchIndex(val)
+		if indexes == nil {
+			return true
+		}
+		target := string(rule.regex.ExpandString(nil, rule.targetLabel, val, indexes))
+		if !model.LabelName(target).IsValid() {
+			return true
+		}
+		if value := string(rule.regex.ExpandString(nil, rule.replacement, val, indexes)); len(value) != 0 {
+			labels[target] = value
+		} else {
+			delete(labels, target)
+		}
+	case ActionHashMod:
+		labels[rule.targetLabel] = strconv.FormatUint(sum64(md5.Sum([]byte(val)))%rule.modulus, 10)
+	case ActionLabelMap:
+		mapped := make(map[string]string)
+		for name, value := range labels {
+			if rule.regex.MatchString(name) {
+				mapped[rule.regex.ReplaceAllString(name, rule.replacement)] = value
+			}
+		}
+		for name, value := range mapped {
+			labels[name] = value
+		}
+	case ActionLabelDrop:
+		for name := range labels {
+			if rule.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+// Process returns a copy of labels with the rules applied in order, keep is false if a rule dropped the
+// metric. The labels with a "__" prefix(except the metric name) are removed at the end, so they can be
+// used as temporary labels.
+func Process(labels map[string]string, rules []Rule) (result map[string]string, keep bool) {
+	result = make(map[string]string, len(labels))
+	for name, value := range labels {
+		result[name] = value
+	}
+	for _, rule := range rules {
+		if !rule.apply(result) {
+			return nil, false
+		}
+	}
+	for name := range result {
+		if strings.HasPrefix(name, model.ReservedLabelPrefix) && name != model.MetricNameLabel {
+			delete(result, name)
+		}
+	}
+	return result, true
+}
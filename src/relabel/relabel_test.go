@@ -0,0 +1,140 @@
+package relabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type relabelSuit struct {
+	suite.Suite
+	labels map[string]string
+}
+
+func (suite *relabelSuit) SetupTest() {
+	suite.labels = map[string]string{
+		"__name__": "skycoin_wallet_seq",
+		"service":  "wallet",
+		"location": "127.0.0.1",
+	}
+}
+
+func TestRelabelSuit(t *testing.T) {
+	suite.Run(t, new(relabelSuit))
+}
+
+func (suite *relabelSuit) process(confs ...Config) (map[string]string, bool) {
+	rules, err := CompileAll(confs)
+	suite.Require().Nil(err)
+	return Process(suite.labels, rules)
+}
+
+func (suite *relabelSuit) TestReplace() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	env := "production"
+	empty := ""
+
+	// NOTE(denisacostaq@gmail.com): When
+	renamed, renamedKeep := suite.process(Config{
+		SourceLabels: []string{"__name__"},
+		Regex:        "skycoin_wallet_(.*)",
+		TargetLabel:  "__name__",
+		Replacement:  stringPtr("legacy_${1}"),
+	})
+	added, addedKeep := suite.process(Config{TargetLabel: "env", Replacement: &env})
+	joined, joinedKeep := suite.process(Config{SourceLabels: []string{"service", "location"}, Separator: "@", TargetLabel: "instance"})
+	removed, removedKeep := suite.process(Config{TargetLabel: "location", Replacement: &empty})
+	notMatched, notMatchedKeep := suite.process(Config{SourceLabels: []string{"service"}, Regex: "node", TargetLabel: "env", Replacement: &env})
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(renamedKeep)
+	suite.Equal("legacy_seq", renamed["__name__"])
+	suite.True(addedKeep)
+	suite.Equal("production", added["env"])
+	suite.True(joinedKeep)
+	suite.Equal("wallet@127.0.0.1", joined["instance"])
+	suite.True(removedKeep)
+	suite.NotContains(removed, "location")
+	suite.True(notMatchedKeep)
+	suite.Equal(suite.labels, notMatched)
+	suite.Equal("skycoin_wallet_seq", suite.labels["__name__"])
+}
+
+func (suite *relabelSuit) TestKeepAndDrop() {
+	// NOTE(denisacostaq@gmail.com): Giving
+
+	// NOTE(denisacostaq@gmail.com): When
+	_, kept := suite.process(Config{SourceLabels: []string{"__name__"}, Regex: "skycoin_.*", Action: ActionKeep})
+	_, notKept := suite.process(Config{SourceLabels: []string{"__name__"}, Regex: "skycoin", Action: ActionKeep})
+	_, dropped := suite.process(Config{SourceLabels: []string{"service"}, Regex: "wall.*", Action: ActionDrop})
+	_, notDropped := suite.process(Config{SourceLabels: []string{"service"}, Regex: "node", Action: ActionDrop})
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(kept)
+	suite.False(notKept)
+	suite.False(dropped)
+	suite.True(notDropped)
+}
+
+func (suite *relabelSuit) TestLabelDropAndLabelMap() {
+	// NOTE(denisacostaq@gmail.com): Giving
+
+	// NOTE(denisacostaq@gmail.com): When
+	dropped, droppedKeep := suite.process(Config{Regex: "loc.*", Action: ActionLabelDrop})
+	mapped, mappedKeep := suite.process(Config{Regex: "(service|location)", Replacement: stringPtr("wallet_${1}"), Action: ActionLabelMap})
+	temporary, temporaryKeep := suite.process(
+		Config{SourceLabels: []string{"service"}, TargetLabel: "__tmp_service"},
+		Config{SourceLabels: []string{"__tmp_service"}, TargetLabel: "node"},
+	)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(droppedKeep)
+	suite.Equal(map[string]string{"__name__": "skycoin_wallet_seq", "service": "wallet"}, dropped)
+	suite.True(mappedKeep)
+	suite.Equal("wallet", mapped["wallet_service"])
+	suite.Equal("127.0.0.1", mapped["wallet_location"])
+	suite.Equal("wallet", mapped["service"])
+	suite.True(temporaryKeep)
+	suite.Equal("wallet", temporary["node"])
+	suite.NotContains(temporary, "__tmp_service")
+}
+
+func (suite *relabelSuit) TestHashMod() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	conf := Config{SourceLabels: []string{"service"}, TargetLabel: "shard", Modulus: 4, Action: ActionHashMod}
+
+	// NOTE(denisacostaq@gmail.com): When
+	first, firstKeep := suite.process(conf)
+	second, _ := suite.process(conf)
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.True(firstKeep)
+	suite.Contains([]string{"0", "1", "2", "3"}, first["shard"])
+	suite.Equal(first["shard"], second["shard"])
+}
+
+func (suite *relabelSuit) TestCompileErrors() {
+	// NOTE(denisacostaq@gmail.com): Giving
+	confs := []Config{
+		Config{Action: "rename", TargetLabel: "env"},
+		Config{Regex: "(", TargetLabel: "env"},
+		Config{Action: ActionReplace},
+		Config{TargetLabel: "not-valid"},
+		Config{Action: ActionHashMod, TargetLabel: "shard"},
+		Config{Action: ActionHashMod, Modulus: 2},
+	}
+
+	// NOTE(denisacostaq@gmail.com): When
+	_, validErr := Compile(Config{Action: "LabelDrop", Regex: "location"})
+
+	// NOTE(denisacostaq@gmail.com): Assert
+	suite.Nil(validErr)
+	for _, conf := range confs {
+		_, err := Compile(conf)
+		suite.NotNil(err, "%+v", conf)
+	}
+}
+
+func stringPtr(str string) *string {
+	return &str
+}
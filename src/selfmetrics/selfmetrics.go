@@ -0,0 +1,202 @@
+// Package selfmetrics has the rextporter own operational metrics, they are registered in a separated
+// registry so they never collide with the metrics got from the services.
+package selfmetrics
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "rextporter"
+
+var (
+	// Version is the rextporter version, it can be set at build time through
+	// -ldflags "-X github.com/simelo/rextporter/src/selfmetrics.Version=x.y.z"
+	Version = "dev"
+	// Revision is the git commit from which rextporter was built, it can be set at build time like Version.
+	Revision = "unknown"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	upstreamRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_requests_total",
+			Help:      "Requests done to the services by service, endpoint and http status code(\"error\" if there was not a response).",
+		},
+		[]string{"service", "endpoint", "status"},
+	)
+	tokenRefreshes = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "token_refreshes_total",
+			Help:      "CSRF tokens requested to the services.",
+		},
+		[]string{"service"},
+	)
+	decodeFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "json_decode_failures_total",
+			Help:      "Responses from the services who can not be decoded as json.",
+		},
+		[]string{"service", "endpoint"},
+	)
+	pathLookupFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "path_lookup_failures_total",
+			Help:      "Metric paths who can not be located in the json documents got from the services.",
+		},
+		[]string{"service", "endpoint"},
+	)
+	unknownValues = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "unknown_values_total",
+			Help:      "String values who are not in the metric mapping(values, states or regex).",
+		},
+		[]string{"service", "metric"},
+	)
+	counterResets = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "counter_resets_total",
+			Help:      "Decreases in the upstream value of the accumulated counters, treated as a counter reset.",
+		},
+		[]string{"service", "metric"},
+	)
+	remoteWriteSamples = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "remote_write_samples_total",
+			Help:      "Samples for the remote write endpoint by result(\"sent\", \"failed\" or \"dropped\" because the queue was full).",
+		},
+		[]string{"result"},
+	)
+	sinkSamples = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "sink_samples_total",
+			Help:      "Samples for the Graphite and StatsD sinks by sink and result(\"sent\" or \"failed\").",
+		},
+		[]string{"sink", "result"},
+	)
+	otlpDataPoints = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "otlp_data_points_total",
+			Help:      "Data points for the OTLP endpoint by result(\"sent\" or \"failed\").",
+		},
+		[]string{"result"},
+	)
+	collectionDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "collection_duration_seconds",
+			Help:      "Time spent collecting the metrics from all the services in a scrape.",
+		},
+	)
+	buildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "build_info",
+			Help:      "A metric with a constant '1' value labeled by the version and revision from which rextporter was built.",
+		},
+		[]string{"version", "revision", "goversion"},
+	)
+	configLoadTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "config_last_load_timestamp_seconds",
+			Help:      "Unix time in which the config was success loaded for last time.",
+		},
+	)
+)
+
+func init() {
+	registry.MustRegister(
+		upstreamRequests,
+		tokenRefreshes,
+		decodeFailures,
+		pathLookupFailures,
+		unknownValues,
+		counterResets,
+		remoteWriteSamples,
+		sinkSamples,
+		otlpDataPoints,
+		collectionDuration,
+		buildInfo,
+		configLoadTimestamp,
+	)
+	buildInfo.WithLabelValues(Version, Revision, runtime.Version()).Set(1)
+}
+
+// Registry returns the registry with the rextporter own metrics.
+func Registry() *prometheus.Registry {
+	return registry
+}
+
+// UpstreamRequest count a request to the endpoint in service, statusCode is 0 if there was not a response.
+func UpstreamRequest(service, endpoint string, statusCode int) {
+	status := "error"
+	if statusCode != 0 {
+		status = strconv.Itoa(statusCode)
+	}
+	upstreamRequests.WithLabelValues(service, endpoint, status).Inc()
+}
+
+// TokenRefresh count a new token requested to service.
+func TokenRefresh(service string) {
+	tokenRefreshes.WithLabelValues(service).Inc()
+}
+
+// DecodeFailure count a response from the endpoint in service who can not be decoded.
+func DecodeFailure(service, endpoint string) {
+	decodeFailures.WithLabelValues(service, endpoint).Inc()
+}
+
+// PathLookupFailure count a metric path who can not be located in the document from the endpoint in service.
+func PathLookupFailure(service, endpoint string) {
+	pathLookupFailures.WithLabelValues(service, endpoint).Inc()
+}
+
+// UnknownValue count a value who is not in the mapping for metric in service.
+func UnknownValue(service, metric string) {
+	unknownValues.WithLabelValues(service, metric).Inc()
+}
+
+// CounterReset count a reset detected in the upstream value for the accumulated counter metric in service.
+func CounterReset(service, metric string) {
+	counterResets.WithLabelValues(service, metric).Inc()
+}
+
+// RemoteWriteSamples count samples for the remote write endpoint with result, sa the remote_write_samples_total metric.
+func RemoteWriteSamples(result string, count int) {
+	remoteWriteSamples.WithLabelValues(result).Add(float64(count))
+}
+
+// SinkSamples count samples for the sink with result, sa the sink_samples_total metric.
+func SinkSamples(sink, result string, count int) {
+	sinkSamples.WithLabelValues(sink, result).Add(float64(count))
+}
+
+// OTLPDataPoints count data points for the OTLP endpoint with result, sa the otlp_data_points_total metric.
+func OTLPDataPoints(result string, count int) {
+	otlpDataPoints.WithLabelValues(result).Add(float64(count))
+}
+
+// ObserveCollection record how long it takes to collect the metrics in a scrape.
+func ObserveCollection(duration time.Duration) {
+	collectionDuration.Observe(duration.Seconds())
+}
+
+// ConfigLoaded record at as the last time the config was loaded.
+func ConfigLoaded(at time.Time) {
+	configLoadTimestamp.Set(float64(at.UnixNano()) / 1e9)
+}
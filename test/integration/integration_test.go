@@ -219,7 +219,7 @@ func (suite *HealthSuit) TestMetricMonitorHealth() {
 	mainConfFilePath, err := createMainConfigTestPaths()
 	require.Nil(err)
 	port := testrand.RandomPort()
-	srv := exporter.ExportMetrics(mainConfFilePath, "/metrics", port)
+	srv := exporter.ExportMetrics(mainConfFilePath, "/metrics", "", port)
 	require.NotNil(srv)
 	// NOTE(denisacostaq@gmail.com): Wait for server starts
 	time.Sleep(time.Second * 2)
@@ -243,7 +243,7 @@ func (suite *HealthSuit) TestConfigWorks() {
 	// NOTE(denisacostaq@gmail.com): Giving
 	require := require.New(suite.T())
 	port := uint16(8000)
-	srv := exporter.ExportMetrics("", "/metrics2", port)
+	srv := exporter.ExportMetrics("", "/metrics2", "", port)
 	require.NotNil(srv)
 	// NOTE(denisacostaq@gmail.com): Wait for server starts
 	time.Sleep(time.Second * 2)